@@ -0,0 +1,74 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/slack-go/slack"
+)
+
+// loadBlocks reads the raw Block Kit payload from SLACK_BLOCKS or
+// SLACK_BLOCKS_FILE (mutually exclusive) and stores the parsed blocks on cfg so
+// content() can send them. It is a no-op when neither is set.
+func loadBlocks(cfg *config) error {
+	if cfg.Blocks != "" && cfg.BlocksFile != "" {
+		return errors.New("SLACK_BLOCKS and SLACK_BLOCKS_FILE are mutually exclusive")
+	}
+
+	data := []byte(cfg.Blocks)
+	if cfg.BlocksFile != "" {
+		var err error
+		data, err = os.ReadFile(cfg.BlocksFile)
+		if err != nil {
+			return fmt.Errorf("read SLACK_BLOCKS_FILE: %w", err)
+		}
+	}
+	if len(bytes.TrimSpace(data)) == 0 {
+		return nil
+	}
+
+	blocks, err := parseBlocks(data)
+	if err != nil {
+		return err
+	}
+	cfg.blocks = blocks
+	return nil
+}
+
+// parseBlocks validates a Block Kit payload by unmarshalling it into
+// slack.Blocks. Both a bare array of blocks and the {"blocks": [...]} object
+// exported by Block Kit Builder are accepted.
+func parseBlocks(data []byte) ([]slack.Block, error) {
+	data = bytes.TrimSpace(data)
+
+	raw := data
+	if len(data) > 0 && data[0] == '{' {
+		var wrapper struct {
+			Blocks json.RawMessage `json:"blocks"`
+		}
+		if err := json.Unmarshal(data, &wrapper); err != nil {
+			return nil, fmt.Errorf("invalid Block Kit payload: %w", err)
+		}
+		if wrapper.Blocks == nil {
+			return nil, errors.New(`invalid Block Kit payload: object has no "blocks" field`)
+		}
+		raw = wrapper.Blocks
+	}
+
+	var blocks slack.Blocks
+	if err := json.Unmarshal(raw, &blocks); err != nil {
+		return nil, fmt.Errorf("invalid Block Kit payload: %w", err)
+	}
+	if len(blocks.BlockSet) == 0 {
+		return nil, errors.New("invalid Block Kit payload: no blocks")
+	}
+	for i, b := range blocks.BlockSet {
+		if b.BlockType() == "" {
+			return nil, fmt.Errorf("invalid Block Kit payload: block %d has no type", i)
+		}
+	}
+	return blocks.BlockSet, nil
+}
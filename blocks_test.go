@@ -0,0 +1,141 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/slack-go/slack"
+	"github.com/stretchr/testify/require"
+)
+
+// capturePostMessage sends the options through a real *slack.Client pointed at
+// a local server and returns the form values Slack would have received.
+func capturePostMessage(t *testing.T, options ...slack.MsgOption) url.Values {
+	t.Helper()
+
+	var values url.Values
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, r.ParseForm())
+		values = r.PostForm
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"ok":true,"channel":"C123","ts":"111.222"}`))
+	}))
+	t.Cleanup(srv.Close)
+
+	client := slack.New("xoxb-test", slack.OptionAPIURL(srv.URL+"/"))
+	_, _, err := client.PostMessage("C123", options...)
+	require.NoError(t, err)
+	return values
+}
+
+func TestParseBlocks(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name      string
+		payload   string
+		types     []slack.MessageBlockType
+		expectErr bool
+	}{
+		{
+			name:    "array",
+			payload: `[{"type":"divider"},{"type":"section","text":{"type":"mrkdwn","text":"hi"}}]`,
+			types:   []slack.MessageBlockType{slack.MBTDivider, slack.MBTSection},
+		},
+		{
+			name:    "builder object",
+			payload: `{"blocks":[{"type":"header","text":{"type":"plain_text","text":"hi"}}]}`,
+			types:   []slack.MessageBlockType{slack.MBTHeader},
+		},
+		{name: "invalid json", payload: `[{`, expectErr: true},
+		{name: "object without blocks", payload: `{"text":"hi"}`, expectErr: true},
+		{name: "empty array", payload: `[]`, expectErr: true},
+		{name: "missing type", payload: `[{"text":"hi"}]`, expectErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			got, err := parseBlocks([]byte(tt.payload))
+			if tt.expectErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			var types []slack.MessageBlockType
+			for _, b := range got {
+				types = append(types, b.BlockType())
+			}
+			require.Equal(t, tt.types, types)
+		})
+	}
+}
+
+func TestLoadBlocks(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "blocks.json")
+	require.NoError(t, os.WriteFile(path, []byte(`[{"type":"divider"}]`), 0644))
+
+	t.Run("from env", func(t *testing.T) {
+		t.Parallel()
+		cfg := config{Blocks: `[{"type":"divider"}]`}
+		require.NoError(t, loadBlocks(&cfg))
+		require.Len(t, cfg.blocks, 1)
+	})
+
+	t.Run("from file", func(t *testing.T) {
+		t.Parallel()
+		cfg := config{BlocksFile: path}
+		require.NoError(t, loadBlocks(&cfg))
+		require.Len(t, cfg.blocks, 1)
+	})
+
+	t.Run("none set", func(t *testing.T) {
+		t.Parallel()
+		cfg := config{}
+		require.NoError(t, loadBlocks(&cfg))
+		require.Nil(t, cfg.blocks)
+	})
+
+	t.Run("both set errors", func(t *testing.T) {
+		t.Parallel()
+		cfg := config{Blocks: `[{"type":"divider"}]`, BlocksFile: path}
+		require.Error(t, loadBlocks(&cfg))
+	})
+
+	t.Run("missing file errors", func(t *testing.T) {
+		t.Parallel()
+		cfg := config{BlocksFile: filepath.Join(t.TempDir(), "nope.json")}
+		require.Error(t, loadBlocks(&cfg))
+	})
+}
+
+func TestContentRawBlocks(t *testing.T) {
+	t.Parallel()
+
+	blocks := []slack.Block{slack.NewDividerBlock()}
+
+	t.Run("top-level blocks", func(t *testing.T) {
+		t.Parallel()
+		cfg := config{Title: "title", Color: "#ff0000", blocks: blocks}
+		values := capturePostMessage(t, content(cfg))
+		require.Equal(t, "title", values.Get("text"))
+		require.JSONEq(t, `[{"type":"divider"}]`, values.Get("blocks"))
+		require.Empty(t, values.Get("attachments"))
+	})
+
+	t.Run("inside attachment", func(t *testing.T) {
+		t.Parallel()
+		cfg := config{Message: "msg", Color: "#ff0000", blocks: blocks, BlocksInAttachment: true}
+		values := capturePostMessage(t, content(cfg))
+		require.Empty(t, values.Get("blocks"))
+		require.Contains(t, values.Get("attachments"), `"color":"#ff0000"`)
+		require.Contains(t, values.Get("attachments"), `"fallback":"msg"`)
+		require.Contains(t, values.Get("attachments"), `"type":"divider"`)
+	})
+}
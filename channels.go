@@ -0,0 +1,154 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/slack-go/slack"
+)
+
+const (
+	channelCacheFile = "channel-cache.json"
+	channelListLimit = 1000
+)
+
+// channelIDRe matches a channel ID: public (C), private (G) or DM (D), or a
+// user ID (U, or W for grid users), which chat.postMessage accepts to DM the
+// user. Channel names are lowercase, so they never match.
+var channelIDRe = regexp.MustCompile(`^[CGDUW][A-Z0-9]{6,}$`)
+
+// slackChannelLister is the subset of *slack.Client used to look up channel
+// IDs by name.
+type slackChannelLister interface {
+	GetConversationsContext(ctx context.Context, params *slack.GetConversationsParameters) ([]slack.Channel, string, error)
+}
+
+// channelResolver turns channel names into IDs, which chat.update,
+// chat.delete, conversations.invite and friends require. Names are looked up
+// with conversations.list and cached in the output directory, so later runs
+// sharing it don't page through every channel again. It is safe for
+// concurrent use.
+type channelResolver struct {
+	client    slackChannelLister
+	cachePath string
+
+	mu     sync.Mutex
+	ids    map[string]string
+	listed bool
+}
+
+// newChannelResolver returns a resolver caching in dir, or only in memory when
+// dir is empty.
+func newChannelResolver(client slackChannelLister, dir string) *channelResolver {
+	r := &channelResolver{client: client}
+	if dir != "" {
+		r.cachePath = filepath.Join(dir, channelCacheFile)
+	}
+	return r
+}
+
+// resolve returns the ID of channel, given as an ID, "#name" or a bare name.
+func (r *channelResolver) resolve(ctx context.Context, channel string) (string, error) {
+	name := strings.TrimPrefix(channel, "#")
+	if channelIDRe.MatchString(name) {
+		return name, nil
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.ids == nil {
+		r.ids = r.loadCache()
+	}
+	if id, ok := r.ids[name]; ok {
+		return id, nil
+	}
+
+	if !r.listed {
+		if err := r.list(ctx); err != nil {
+			return "", fmt.Errorf("resolve channel %s: %w", channel, err)
+		}
+		r.saveCache()
+	}
+	id, ok := r.ids[name]
+	if !ok {
+		return "", fmt.Errorf("resolve channel %s: %w", channel, slack.SlackErrorResponse{Err: "channel_not_found"})
+	}
+	slog.Info("Channel resolved", "channel", channel, "channel_id", id)
+	return id, nil
+}
+
+// channelIDRequired reports whether the operation needs the channel ID rather
+// than a name: updates, deletes, reactions and uploads do, while posting,
+// replying and scheduling accept the name as given.
+func channelIDRequired(cfg config) bool {
+	return cfg.UpdateTs != "" || cfg.DeleteTs != "" || cfg.DeleteScheduledID != "" ||
+		cfg.ReactionTs != "" || cfg.command == commandUpload
+}
+
+// list pages through every public and private channel the bot can see.
+func (r *channelResolver) list(ctx context.Context) error {
+	params := &slack.GetConversationsParameters{
+		ExcludeArchived: true,
+		Limit:           channelListLimit,
+		Types:           []string{"public_channel", "private_channel"},
+	}
+	for {
+		channels, cursor, err := r.client.GetConversationsContext(ctx, params)
+		if err != nil {
+			return err
+		}
+		for _, ch := range channels {
+			r.ids[ch.Name] = ch.ID
+		}
+		if cursor == "" {
+			break
+		}
+		params.Cursor = cursor
+	}
+	r.listed = true
+	return nil
+}
+
+// loadCache reads the cache left by an earlier run. A missing or unreadable
+// cache only means listing the channels again.
+func (r *channelResolver) loadCache() map[string]string {
+	ids := make(map[string]string)
+	if r.cachePath == "" {
+		return ids
+	}
+	data, err := os.ReadFile(r.cachePath)
+	if err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			slog.Warn("Failed to read channel cache", "path", r.cachePath, "error", err)
+		}
+		return ids
+	}
+	if err := json.Unmarshal(data, &ids); err != nil {
+		slog.Warn("Ignoring invalid channel cache", "path", r.cachePath, "error", err)
+		return make(map[string]string)
+	}
+	return ids
+}
+
+// saveCache is best-effort: a failed write never fails the run.
+func (r *channelResolver) saveCache() {
+	if r.cachePath == "" {
+		return
+	}
+	data, err := json.MarshalIndent(r.ids, "", "  ")
+	if err == nil {
+		err = os.WriteFile(r.cachePath, data, 0644)
+	}
+	if err != nil {
+		slog.Warn("Failed to write channel cache", "path", r.cachePath, "error", err)
+	}
+}
@@ -0,0 +1,178 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/slack-go/slack"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeChannelLister serves pages of channels from conversations.list, counting
+// the calls.
+type fakeChannelLister struct {
+	pages [][]slack.Channel
+	err   error
+	calls int
+}
+
+func (f *fakeChannelLister) GetConversationsContext(_ context.Context, params *slack.GetConversationsParameters) ([]slack.Channel, string, error) {
+	f.calls++
+	if f.err != nil {
+		return nil, "", f.err
+	}
+	page := 0
+	if params.Cursor != "" {
+		page = int(params.Cursor[0] - '0')
+	}
+	next := ""
+	if page+1 < len(f.pages) {
+		next = string(rune('0' + page + 1))
+	}
+	return f.pages[page], next, nil
+}
+
+func testChannel(id, name string) slack.Channel {
+	var ch slack.Channel
+	ch.ID, ch.Name = id, name
+	return ch
+}
+
+func TestChannelResolver(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	pages := [][]slack.Channel{
+		{testChannel("C0000001", "general")},
+		{testChannel("G0000002", "deploys-private"), testChannel("C0000003", "deploys")},
+	}
+
+	t.Run("ids pass through", func(t *testing.T) {
+		t.Parallel()
+		lister := &fakeChannelLister{pages: pages}
+		r := newChannelResolver(lister, "")
+		for _, channel := range []string{"C0123ABCD", "#G0123ABCD", "U0123ABCD", "W0123ABCD"} {
+			id, err := r.resolve(ctx, channel)
+			require.NoError(t, err)
+			require.Equal(t, strings.TrimPrefix(channel, "#"), id)
+		}
+		require.Zero(t, lister.calls)
+	})
+
+	t.Run("names across pages", func(t *testing.T) {
+		t.Parallel()
+		lister := &fakeChannelLister{pages: pages}
+		r := newChannelResolver(lister, "")
+		id, err := r.resolve(ctx, "#deploys")
+		require.NoError(t, err)
+		require.Equal(t, "C0000003", id)
+
+		id, err = r.resolve(ctx, "deploys-private")
+		require.NoError(t, err)
+		require.Equal(t, "G0000002", id)
+		require.Equal(t, 2, lister.calls, "one listing serves every lookup")
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		t.Parallel()
+		lister := &fakeChannelLister{pages: pages}
+		r := newChannelResolver(lister, "")
+		_, err := r.resolve(ctx, "#missing")
+		require.Equal(t, exitChannelNotFound, exitCodeFor(err))
+
+		_, err = r.resolve(ctx, "#missing-too")
+		require.Error(t, err)
+		require.Equal(t, 2, lister.calls, "channels are listed once per run")
+	})
+
+	t.Run("list fails", func(t *testing.T) {
+		t.Parallel()
+		r := newChannelResolver(&fakeChannelLister{err: slack.SlackErrorResponse{Err: "missing_scope"}}, "")
+		_, err := r.resolve(ctx, "#deploys")
+		require.Equal(t, exitAuth, exitCodeFor(err))
+	})
+
+	t.Run("cached on disk", func(t *testing.T) {
+		t.Parallel()
+		dir := t.TempDir()
+		_, err := newChannelResolver(&fakeChannelLister{pages: pages}, dir).resolve(ctx, "#general")
+		require.NoError(t, err)
+		require.FileExists(t, filepath.Join(dir, channelCacheFile))
+
+		lister := &fakeChannelLister{err: errors.New("must not be called")}
+		id, err := newChannelResolver(lister, dir).resolve(ctx, "deploys")
+		require.NoError(t, err)
+		require.Equal(t, "C0000003", id)
+		require.Zero(t, lister.calls)
+	})
+
+	t.Run("invalid cache is ignored", func(t *testing.T) {
+		t.Parallel()
+		dir := t.TempDir()
+		require.NoError(t, os.WriteFile(filepath.Join(dir, channelCacheFile), []byte("{"), 0644))
+		id, err := newChannelResolver(&fakeChannelLister{pages: pages}, dir).resolve(ctx, "#general")
+		require.NoError(t, err)
+		require.Equal(t, "C0000001", id)
+	})
+}
+
+func TestExecuteResolvesChannel(t *testing.T) {
+	t.Parallel()
+
+	api := &channelListingSlackAPI{lister: fakeChannelLister{pages: [][]slack.Channel{{testChannel("C0000003", "deploys")}}}}
+	res := &result{}
+	cfg := config{Channel: "#deploys", UpdateTs: "111.222", Message: "updated"}
+	cfg.channels = newChannelResolver(api, "")
+	require.NoError(t, execute(context.Background(), cfg, membershipModeNone, api, res))
+	require.Equal(t, "C0000003", res.ChannelID)
+}
+
+func TestExecuteFallsBackToChannelName(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		cfg     config
+		lister  fakeChannelLister
+		wantErr bool
+	}{
+		{name: "post, list fails", cfg: config{Message: "hi"}, lister: fakeChannelLister{err: slack.SlackErrorResponse{Err: "missing_scope"}}},
+		{name: "reply, not found", cfg: config{ThreadTs: "100.000", Message: "hi"}, lister: fakeChannelLister{pages: [][]slack.Channel{{}}}},
+		{name: "update, list fails", cfg: config{UpdateTs: "111.222"}, lister: fakeChannelLister{err: slack.SlackErrorResponse{Err: "missing_scope"}}, wantErr: true},
+		{name: "react, not found", cfg: config{ReactionTs: "111.222", Reactions: []string{"eyes"}}, lister: fakeChannelLister{pages: [][]slack.Channel{{}}}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			api := &channelListingSlackAPI{lister: tt.lister}
+			cfg := tt.cfg
+			cfg.Channel = "#deploys"
+			cfg.channels = newChannelResolver(api, "")
+			res := &result{}
+			err := execute(context.Background(), cfg, membershipModeNone, api, res)
+			if tt.wantErr {
+				require.Error(t, err)
+				require.Zero(t, api.sendCalls)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, 1, api.sendCalls)
+			require.Equal(t, "#deploys", res.ChannelID)
+		})
+	}
+}
+
+// channelListingSlackAPI is a flakySlackAPI that also lists channels.
+type channelListingSlackAPI struct {
+	flakySlackAPI
+	lister fakeChannelLister
+}
+
+func (f *channelListingSlackAPI) GetConversationsContext(ctx context.Context, params *slack.GetConversationsParameters) ([]slack.Channel, string, error) {
+	return f.lister.GetConversationsContext(ctx, params)
+}
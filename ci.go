@@ -0,0 +1,181 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"strings"
+)
+
+// ciInfo is what a CI provider tells us about the current run. Empty fields
+// are simply left out of the context line.
+type ciInfo struct {
+	Provider   string
+	Repository string
+	RepoURL    string
+	Branch     string
+	Commit     string
+	CommitURL  string
+	Run        string
+	RunURL     string
+	Actor      string
+	// GitHubActor is set only when Actor is known to be a GitHub login, so it
+	// can stand in for GH_USER.
+	GitHubActor string
+}
+
+// detectCI recognizes the provider from the variables it always sets and reads
+// the run details from its well-known variables.
+func detectCI(getenv func(string) string) (ciInfo, bool) {
+	switch {
+	case getenv("GITHUB_ACTIONS") == "true":
+		server := getenv("GITHUB_SERVER_URL")
+		if server == "" {
+			server = "https://github.com"
+		}
+		repo := getenv("GITHUB_REPOSITORY")
+		info := ciInfo{
+			Provider:    "GitHub Actions",
+			Repository:  repo,
+			Branch:      firstNonEmpty(getenv("GITHUB_HEAD_REF"), getenv("GITHUB_REF_NAME")),
+			Commit:      getenv("GITHUB_SHA"),
+			Actor:       getenv("GITHUB_ACTOR"),
+			GitHubActor: getenv("GITHUB_ACTOR"),
+		}
+		if n := getenv("GITHUB_RUN_NUMBER"); n != "" {
+			info.Run = "run #" + n
+		}
+		if repo != "" {
+			info.RepoURL = server + "/" + repo
+			if info.Commit != "" {
+				info.CommitURL = info.RepoURL + "/commit/" + info.Commit
+			}
+			if id := getenv("GITHUB_RUN_ID"); id != "" {
+				info.RunURL = info.RepoURL + "/actions/runs/" + id
+			}
+		}
+		return info, true
+
+	case getenv("GITLAB_CI") == "true":
+		info := ciInfo{
+			Provider:   "GitLab CI",
+			Repository: getenv("CI_PROJECT_PATH"),
+			RepoURL:    getenv("CI_PROJECT_URL"),
+			Branch:     firstNonEmpty(getenv("CI_MERGE_REQUEST_SOURCE_BRANCH_NAME"), getenv("CI_COMMIT_REF_NAME")),
+			Commit:     getenv("CI_COMMIT_SHA"),
+			RunURL:     getenv("CI_PIPELINE_URL"),
+			Actor:      getenv("GITLAB_USER_LOGIN"),
+		}
+		if n := getenv("CI_PIPELINE_IID"); n != "" {
+			info.Run = "pipeline #" + n
+		}
+		if info.RepoURL != "" && info.Commit != "" {
+			info.CommitURL = info.RepoURL + "/-/commit/" + info.Commit
+		}
+		return info, true
+
+	case getenv("DRONE") == "true":
+		info := ciInfo{
+			Provider:   "Drone",
+			Repository: getenv("DRONE_REPO"),
+			RepoURL:    getenv("DRONE_REPO_LINK"),
+			Branch:     firstNonEmpty(getenv("DRONE_SOURCE_BRANCH"), getenv("DRONE_BRANCH")),
+			Commit:     getenv("DRONE_COMMIT_SHA"),
+			CommitURL:  getenv("DRONE_COMMIT_LINK"),
+			RunURL:     getenv("DRONE_BUILD_LINK"),
+			Actor:      getenv("DRONE_COMMIT_AUTHOR"),
+		}
+		if n := getenv("DRONE_BUILD_NUMBER"); n != "" {
+			info.Run = "build #" + n
+		}
+		return info, true
+
+	case getenv("ARGO_NODE_ID") != "" || getenv("ARGO_TEMPLATE") != "":
+		// Argo doesn't expose workflow metadata to containers on its own; these
+		// are expected to be passed in from {{workflow.name}} and friends.
+		info := ciInfo{
+			Provider:   "Argo Workflows",
+			Repository: getenv("ARGO_REPOSITORY"),
+			Branch:     getenv("ARGO_BRANCH"),
+			Commit:     getenv("ARGO_COMMIT_SHA"),
+			Run:        getenv("ARGO_WORKFLOW_NAME"),
+			RunURL:     getenv("ARGO_WORKFLOW_URL"),
+			Actor:      getenv("ARGO_ACTOR"),
+		}
+		return info, true
+	}
+	return ciInfo{}, false
+}
+
+// contextLine renders the run as a single mrkdwn line, e.g.
+// "<repo-url|org/repo> · `main` · <commit-url|abc1234> · <run-url|run #42> · by octocat".
+func (c ciInfo) contextLine() string {
+	var parts []string
+	if c.Repository != "" {
+		parts = append(parts, mrkdwnLink(c.RepoURL, c.Repository))
+	}
+	if c.Branch != "" {
+		parts = append(parts, "`"+c.Branch+"`")
+	}
+	if c.Commit != "" {
+		parts = append(parts, mrkdwnLink(c.CommitURL, shortSHA(c.Commit)))
+	}
+	run := c.Run
+	if run == "" && c.RunURL != "" {
+		run = c.Provider + " run"
+	}
+	if run != "" {
+		parts = append(parts, mrkdwnLink(c.RunURL, run))
+	}
+	if c.Actor != "" {
+		parts = append(parts, "by "+slackEscape(c.Actor))
+	}
+	return strings.Join(parts, " · ")
+}
+
+// applyCIContext adds the detected CI details to the context block and, on
+// GitHub Actions, uses GITHUB_ACTOR as GH_USER when none was given.
+func applyCIContext(cfg *config, getenv func(string) string) error {
+	switch cfg.CIContext {
+	case "", "off":
+		return nil
+	case "auto":
+	default:
+		return fmt.Errorf("invalid SLACK_CI_CONTEXT %q (valid: off, auto)", cfg.CIContext)
+	}
+
+	info, ok := detectCI(getenv)
+	if !ok {
+		slog.Info("No CI provider detected, skipping CI context")
+		return nil
+	}
+	slog.Info("CI provider detected", "provider", info.Provider)
+
+	if line := info.contextLine(); line != "" {
+		if cfg.Context == "" {
+			cfg.Context = line
+		} else {
+			cfg.Context += "\n" + line
+		}
+	}
+
+	if cfg.GitHubUser == "" && info.GitHubActor != "" {
+		cfg.GitHubUser = info.GitHubActor
+	}
+	return nil
+}
+
+func shortSHA(sha string) string {
+	if len(sha) > 7 {
+		return sha[:7]
+	}
+	return sha
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
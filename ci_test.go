@@ -0,0 +1,127 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func mapEnv(env map[string]string) func(string) string {
+	return func(key string) string { return env[key] }
+}
+
+func TestDetectCI(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		env      map[string]string
+		expected string
+		ok       bool
+	}{
+		{
+			name: "github actions",
+			env: map[string]string{
+				"GITHUB_ACTIONS":    "true",
+				"GITHUB_SERVER_URL": "https://github.com",
+				"GITHUB_REPOSITORY": "grafana/docker-slack-message",
+				"GITHUB_REF_NAME":   "main",
+				"GITHUB_SHA":        "0123456789abcdef",
+				"GITHUB_RUN_ID":     "987",
+				"GITHUB_RUN_NUMBER": "42",
+				"GITHUB_ACTOR":      "octocat",
+			},
+			expected: "<https://github.com/grafana/docker-slack-message|grafana/docker-slack-message> · `main` · " +
+				"<https://github.com/grafana/docker-slack-message/commit/0123456789abcdef|0123456> · " +
+				"<https://github.com/grafana/docker-slack-message/actions/runs/987|run #42> · by octocat",
+			ok: true,
+		},
+		{
+			name: "gitlab",
+			env: map[string]string{
+				"GITLAB_CI":          "true",
+				"CI_PROJECT_PATH":    "group/project",
+				"CI_PROJECT_URL":     "https://gitlab.com/group/project",
+				"CI_COMMIT_REF_NAME": "feature",
+				"CI_COMMIT_SHA":      "abcdef0123456",
+				"CI_PIPELINE_URL":    "https://gitlab.com/group/project/-/pipelines/5",
+				"CI_PIPELINE_IID":    "5",
+			},
+			expected: "<https://gitlab.com/group/project|group/project> · `feature` · " +
+				"<https://gitlab.com/group/project/-/commit/abcdef0123456|abcdef0> · " +
+				"<https://gitlab.com/group/project/-/pipelines/5|pipeline #5>",
+			ok: true,
+		},
+		{
+			name: "drone",
+			env: map[string]string{
+				"DRONE":               "true",
+				"DRONE_REPO":          "org/repo",
+				"DRONE_BRANCH":        "main",
+				"DRONE_COMMIT_SHA":    "1234567890",
+				"DRONE_BUILD_LINK":    "https://drone.example/org/repo/7",
+				"DRONE_COMMIT_AUTHOR": "hubot",
+			},
+			expected: "org/repo · `main` · 1234567 · <https://drone.example/org/repo/7|Drone run> · by hubot",
+			ok:       true,
+		},
+		{
+			name: "argo",
+			env: map[string]string{
+				"ARGO_NODE_ID":       "deploy-abc-123",
+				"ARGO_WORKFLOW_NAME": "deploy-abc",
+				"ARGO_WORKFLOW_URL":  "https://argo.example/workflows/ns/deploy-abc",
+			},
+			expected: "<https://argo.example/workflows/ns/deploy-abc|deploy-abc>",
+			ok:       true,
+		},
+		{name: "none", env: map[string]string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			info, ok := detectCI(mapEnv(tt.env))
+			require.Equal(t, tt.ok, ok)
+			require.Equal(t, tt.expected, info.contextLine())
+		})
+	}
+}
+
+func TestApplyCIContext(t *testing.T) {
+	t.Parallel()
+
+	env := mapEnv(map[string]string{
+		"GITHUB_ACTIONS":    "true",
+		"GITHUB_REPOSITORY": "org/repo",
+		"GITHUB_ACTOR":      "octocat",
+	})
+
+	t.Run("off leaves config alone", func(t *testing.T) {
+		t.Parallel()
+		cfg := config{Context: "ctx"}
+		require.NoError(t, applyCIContext(&cfg, env))
+		require.Equal(t, config{Context: "ctx"}, cfg)
+	})
+
+	t.Run("auto appends context and fills GH_USER", func(t *testing.T) {
+		t.Parallel()
+		cfg := config{CIContext: "auto", Context: "ctx"}
+		require.NoError(t, applyCIContext(&cfg, env))
+		require.Equal(t, "ctx\n<https://github.com/org/repo|org/repo> · by octocat", cfg.Context)
+		require.Equal(t, "octocat", cfg.GitHubUser)
+	})
+
+	t.Run("explicit GH_USER wins", func(t *testing.T) {
+		t.Parallel()
+		cfg := config{CIContext: "auto", GitHubUser: "hubot"}
+		require.NoError(t, applyCIContext(&cfg, env))
+		require.Equal(t, "hubot", cfg.GitHubUser)
+	})
+
+	t.Run("invalid value", func(t *testing.T) {
+		t.Parallel()
+		cfg := config{CIContext: "yes"}
+		require.Error(t, applyCIContext(&cfg, env))
+	})
+}
@@ -0,0 +1,291 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"slices"
+	"strings"
+)
+
+const (
+	commandPost     = "post"
+	commandReply    = "reply"
+	commandUpdate   = "update"
+	commandDelete   = "delete"
+	commandReact    = "react"
+	commandUpload   = "upload"
+	commandSchedule = "schedule"
+	commandPreview  = "preview"
+)
+
+const programName = "slack-message"
+
+// cliFlag is a flag standing for the environment variable env. Flags set their
+// variable before the config is loaded, so both go through the same parsing
+// and validation and a flag always wins over the variable.
+type cliFlag struct {
+	name  string
+	env   string
+	usage string
+	bool  bool
+}
+
+// command is a subcommand. The operation of an env-only run is implied by the
+// *_TS variables set; a command names it, requiring one of the variables in
+// targets and rejecting the other targetVars. env is set before the flags are
+// applied.
+type command struct {
+	name    string
+	args    string
+	summary string
+	flags   []cliFlag
+	targets []string
+	allowed []string
+	env     map[string]string
+}
+
+// targetVars are the variables that pick which message a run acts on, and so
+// its operation.
+var targetVars = []string{
+	"SLACK_THREAD_TS",
+	"SLACK_UPDATE_MESSAGE_TS",
+	"SLACK_DELETE_MESSAGE_TS",
+	"SLACK_MANIFEST_FILE",
+	"SLACK_POST_AT",
+	"SLACK_DELETE_SCHEDULED_ID",
+	"SLACK_REACTION_TS",
+}
+
+var (
+	commonFlags = []cliFlag{
+		{name: "channel", env: "SLACK_CHANNEL", usage: "channel name or ID, @github:<login> or @slack:<id> for a DM, or a comma-separated list"},
+		{name: "output-dir", env: "SLACK_OUTPUT_DIR", usage: "directory the outputs are written to"},
+		{name: "dry-run", env: "SLACK_DRY_RUN", usage: "print the Slack API requests instead of sending them", bool: true},
+	}
+	contentFlags = []cliFlag{
+		{name: "title", env: "SLACK_TITLE", usage: "message title"},
+		{name: "message", env: "SLACK_MESSAGE", usage: "message text, in mrkdwn"},
+		{name: "context", env: "SLACK_CONTEXT", usage: "context line below the message"},
+		{name: "color", env: "SLACK_COLOR", usage: "attachment color"},
+		{name: "status", env: "SLACK_STATUS", usage: "status preset: success, failure, warning, cancelled, in_progress or skipped"},
+		{name: "blocks-file", env: "SLACK_BLOCKS_FILE", usage: "file with a Block Kit payload replacing the title, message and context"},
+		{name: "template", env: "SLACK_TEMPLATE", usage: "render the title, message and context as Go templates", bool: true},
+		{name: "template-data-file", env: "SLACK_TEMPLATE_DATA_FILE", usage: "JSON file available to the templates as {{ .Data }}"},
+		{name: "ci-context", env: "SLACK_CI_CONTEXT", usage: "auto to add the CI run details to the context"},
+	}
+	mentionFlags = []cliFlag{
+		{name: "gh-user", env: "GH_USER", usage: "GitHub login to mention"},
+		{name: "mention-membership-mode", env: "SLACK_MENTION_MEMBERSHIP_MODE", usage: "none, invite, notify or smart"},
+	}
+	threadFlag    = cliFlag{name: "thread-ts", env: "SLACK_THREAD_TS", usage: "timestamp of the thread root"}
+	broadcastFlag = cliFlag{name: "also-send-to-channel", env: "SLACK_ALSO_SEND_TO_CHANNEL", usage: "also show the reply in the channel", bool: true}
+	filesFlag     = cliFlag{name: "files", env: "SLACK_FILES", usage: "comma-separated glob patterns of files to upload"}
+	manifestFlag  = cliFlag{name: "manifest", env: "SLACK_MANIFEST_FILE", usage: "manifest.json of an earlier run, to act on every message in it"}
+)
+
+func flags(groups ...[]cliFlag) []cliFlag {
+	return slices.Concat(groups...)
+}
+
+var commands = []command{
+	{
+		name:    commandPost,
+		summary: "Post a new message",
+		flags: flags(commonFlags, contentFlags, mentionFlags, []cliFlag{
+			filesFlag,
+			{name: "ephemeral-user", env: "SLACK_EPHEMERAL_USER", usage: "Slack user ID or GitHub login the message is only visible to"},
+		}),
+	},
+	{
+		name:    commandReply,
+		summary: "Reply in a thread",
+		flags: flags(commonFlags, contentFlags, mentionFlags, []cliFlag{
+			threadFlag, broadcastFlag, filesFlag,
+			{name: "root-update-title", env: "SLACK_ROOT_UPDATE_TITLE", usage: "title to rewrite the thread root with"},
+			{name: "root-update-message", env: "SLACK_ROOT_UPDATE_MESSAGE", usage: "message to rewrite the thread root with"},
+			{name: "root-update-context", env: "SLACK_ROOT_UPDATE_CONTEXT", usage: "context to rewrite the thread root with"},
+			{name: "root-update-color", env: "SLACK_ROOT_UPDATE_COLOR", usage: "attachment color of the rewritten thread root"},
+			{name: "root-update-status", env: "SLACK_ROOT_UPDATE_STATUS", usage: "status preset of the rewritten thread root"},
+		}),
+		targets: []string{"SLACK_THREAD_TS"},
+	},
+	{
+		name:    commandUpdate,
+		summary: "Update a message, or every message in a manifest",
+		flags: flags(commonFlags, contentFlags, []cliFlag{
+			{name: "ts", env: "SLACK_UPDATE_MESSAGE_TS", usage: "timestamp of the message to update"},
+			manifestFlag, threadFlag,
+		}),
+		targets: []string{"SLACK_UPDATE_MESSAGE_TS", "SLACK_MANIFEST_FILE"},
+		allowed: []string{"SLACK_THREAD_TS"},
+		env:     map[string]string{"SLACK_MANIFEST_ACTION": "update"},
+	},
+	{
+		name:    commandDelete,
+		summary: "Delete a message, every message in a manifest, or a scheduled message",
+		flags: flags(commonFlags, []cliFlag{
+			{name: "ts", env: "SLACK_DELETE_MESSAGE_TS", usage: "timestamp of the message to delete"},
+			manifestFlag,
+			{name: "scheduled-id", env: "SLACK_DELETE_SCHEDULED_ID", usage: "ID of the scheduled message to cancel"},
+		}),
+		targets: []string{"SLACK_DELETE_MESSAGE_TS", "SLACK_MANIFEST_FILE", "SLACK_DELETE_SCHEDULED_ID"},
+		env:     map[string]string{"SLACK_MANIFEST_ACTION": "delete"},
+	},
+	{
+		name:    commandReact,
+		summary: "Add emoji reactions to a message",
+		flags: flags(commonFlags, []cliFlag{
+			{name: "ts", env: "SLACK_REACTION_TS", usage: "timestamp of the message to react to"},
+			{name: "emoji", env: "SLACK_REACTIONS", usage: "comma-separated emoji names, e.g. white_check_mark"},
+		}),
+		targets: []string{"SLACK_REACTION_TS"},
+	},
+	{
+		name:    commandUpload,
+		summary: "Upload files without a message",
+		flags:   flags(commonFlags, []cliFlag{filesFlag, threadFlag}),
+		targets: []string{"SLACK_FILES"},
+		allowed: []string{"SLACK_THREAD_TS"},
+	},
+	{
+		name:    commandSchedule,
+		summary: "Schedule a message",
+		flags: flags(commonFlags, contentFlags, mentionFlags, []cliFlag{
+			{name: "at", env: "SLACK_POST_AT", usage: "RFC 3339 time or duration from now to post at"},
+			threadFlag, broadcastFlag,
+		}),
+		targets: []string{"SLACK_POST_AT"},
+		allowed: []string{"SLACK_THREAD_TS"},
+	},
+	{
+		name:    commandPreview,
+		args:    " [file]",
+		summary: "Render the message to an HTML file without sending it",
+		flags: flags([]cliFlag{
+			{name: "channel", env: "SLACK_CHANNEL", usage: "channel shown in the preview"},
+			{name: "output-dir", env: "SLACK_OUTPUT_DIR", usage: "directory preview.html is written to without a file argument"},
+		}, contentFlags),
+	},
+}
+
+func findCommand(name string) (command, bool) {
+	i := slices.IndexFunc(commands, func(c command) bool { return c.name == name })
+	if i < 0 {
+		return command{}, false
+	}
+	return commands[i], true
+}
+
+// parseArgs parses the command line. Without arguments it returns no command,
+// for the env-only invocation. Otherwise it sets the environment variables
+// for the command's flags and returns the command and its positional
+// arguments. Help goes to out and returns flag.ErrHelp.
+func parseArgs(args []string, out io.Writer) (string, []string, error) {
+	if len(args) == 0 {
+		return "", nil, nil
+	}
+	switch args[0] {
+	case "help", "-h", "-help", "--help":
+		printUsage(out)
+		return "", nil, flag.ErrHelp
+	}
+
+	cmd, ok := findCommand(args[0])
+	if !ok {
+		printUsage(out)
+		return "", nil, fmt.Errorf("unknown command %q", args[0])
+	}
+
+	fs := flag.NewFlagSet(cmd.name, flag.ContinueOnError)
+	fs.SetOutput(out)
+	fs.Usage = func() {
+		fmt.Fprintf(out, "Usage: %s %s [flags]%s\n\n%s.\n\nFlags override the environment variables named after them.\n\n", programName, cmd.name, cmd.args, cmd.summary)
+		fs.PrintDefaults()
+	}
+	for _, f := range cmd.flags {
+		usage := fmt.Sprintf("%s (%s)", f.usage, f.env)
+		if f.bool {
+			fs.Bool(f.name, false, usage)
+		} else {
+			fs.String(f.name, "", usage)
+		}
+	}
+	if err := fs.Parse(args[1:]); err != nil {
+		return "", nil, err
+	}
+	if fs.NArg() > 0 && cmd.args == "" {
+		fs.Usage()
+		return "", nil, fmt.Errorf("%s takes no arguments, got %q", cmd.name, fs.Args())
+	}
+
+	for key, value := range cmd.env {
+		if err := os.Setenv(key, value); err != nil {
+			return "", nil, fmt.Errorf("set %s: %w", key, err)
+		}
+	}
+	var err error
+	fs.Visit(func(f *flag.Flag) {
+		i := slices.IndexFunc(cmd.flags, func(c cliFlag) bool { return c.name == f.Name })
+		err = errors.Join(err, os.Setenv(cmd.flags[i].env, f.Value.String()))
+	})
+	if err != nil {
+		return "", nil, fmt.Errorf("apply flags: %w", err)
+	}
+	return cmd.name, fs.Args(), nil
+}
+
+func printUsage(out io.Writer) {
+	fmt.Fprintf(out, "Usage: %s [command] [flags]\n\n", programName)
+	fmt.Fprintln(out, "Sends a Slack message configured by environment variables. Without a command,")
+	fmt.Fprintln(out, "the operation follows from the variables set, e.g. SLACK_THREAD_TS for a reply.")
+	fmt.Fprintln(out, "\nCommands:")
+	for _, c := range commands {
+		fmt.Fprintf(out, "  %-10s %s\n", c.name, c.summary)
+	}
+	fmt.Fprintf(out, "\nRun '%s <command> --help' for the flags of a command.\n", programName)
+}
+
+// validateCommand checks that the configuration fits the command: one of its
+// targets is set and no variable selecting another operation is. Env-only
+// runs have no command and aren't checked.
+func validateCommand(cfg config) error {
+	if cfg.command == "" {
+		return nil
+	}
+	cmd, ok := findCommand(cfg.command)
+	if !ok {
+		return fmt.Errorf("unknown command %q", cfg.command)
+	}
+
+	for _, key := range targetVars {
+		if configSet(cfg, key) && !slices.Contains(cmd.targets, key) && !slices.Contains(cmd.allowed, key) {
+			return fmt.Errorf("%s is set, which %s doesn't take", key, cmd.name)
+		}
+	}
+	if len(cmd.targets) > 0 && !slices.ContainsFunc(cmd.targets, func(key string) bool { return configSet(cfg, key) }) {
+		var names []string
+		for _, key := range cmd.targets {
+			i := slices.IndexFunc(cmd.flags, func(f cliFlag) bool { return f.env == key })
+			names = append(names, fmt.Sprintf("--%s (%s)", cmd.flags[i].name, key))
+		}
+		return fmt.Errorf("%s requires %s", cmd.name, strings.Join(names, " or "))
+	}
+	return nil
+}
+
+// configSet reports whether the config field read from the environment
+// variable key has a value.
+func configSet(cfg config, key string) bool {
+	v := reflect.ValueOf(cfg)
+	t := v.Type()
+	for i := range t.NumField() {
+		if t.Field(i).Tag.Get("envconfig") == key {
+			return !v.Field(i).IsZero()
+		}
+	}
+	return false
+}
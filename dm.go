@@ -0,0 +1,77 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/slack-go/slack"
+)
+
+const (
+	dmGitHubPrefix = "@github:"
+	dmSlackPrefix  = "@slack:"
+)
+
+// isDMTarget reports whether a channel in SLACK_CHANNEL names a user to DM
+// rather than a channel: @github:<login> or @slack:<user ID>.
+func isDMTarget(channel string) bool {
+	return strings.HasPrefix(channel, dmGitHubPrefix) || strings.HasPrefix(channel, dmSlackPrefix)
+}
+
+// validateDMTargets checks the DM targets in SLACK_CHANNEL before anything is
+// sent.
+func validateDMTargets(cfg config) error {
+	for _, ch := range splitChannels(cfg.Channel) {
+		switch {
+		case strings.HasPrefix(ch, dmGitHubPrefix):
+			if strings.TrimPrefix(ch, dmGitHubPrefix) == "" {
+				return fmt.Errorf("SLACK_CHANNEL %q is missing the GitHub login", ch)
+			}
+			if !hasIdentitySource(cfg) {
+				return fmt.Errorf("SLACK_CHANNEL %q needs a GitHub-to-Slack mapping to resolve the user", ch)
+			}
+		case strings.HasPrefix(ch, dmSlackPrefix):
+			if !slackUserIDRe.MatchString(strings.TrimPrefix(ch, dmSlackPrefix)) {
+				return fmt.Errorf("SLACK_CHANNEL %q is not a Slack user ID", ch)
+			}
+		}
+	}
+	return nil
+}
+
+// openDMChannels opens a DM with every user named in SLACK_CHANNEL and returns
+// the DM channel IDs keyed by the target as given. Opening a DM that already
+// exists returns it, so updates can target @github:<login> again.
+func openDMChannels(ctx context.Context, cfg config, identities identityResolver, client slackMembershipClient) (map[string]string, error) {
+	var dms map[string]string
+	for _, ch := range splitChannels(cfg.Channel) {
+		if !isDMTarget(ch) {
+			continue
+		}
+
+		userID, ok := strings.CutPrefix(ch, dmSlackPrefix)
+		if !ok {
+			login := strings.TrimPrefix(ch, dmGitHubPrefix)
+			var err error
+			userID, err = identities.SlackUserID(ctx, login)
+			if err != nil {
+				return nil, fmt.Errorf("resolve %s: %w", ch, err)
+			}
+			slog.Info("Slack ID found", "github_user", login, "slack_user_id", userID)
+		}
+
+		dm, _, _, err := client.OpenConversationContext(ctx, &slack.OpenConversationParameters{Users: []string{userID}})
+		if err != nil {
+			return nil, fmt.Errorf("open DM for %s: %w", ch, err)
+		}
+		slog.Info("DM opened", "target", ch, "channel_id", dm.ID)
+
+		if dms == nil {
+			dms = make(map[string]string)
+		}
+		dms[ch] = dm.ID
+	}
+	return dms, nil
+}
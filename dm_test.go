@@ -0,0 +1,105 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateDMTargets(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name      string
+		cfg       config
+		expectErr bool
+	}{
+		{name: "channels only", cfg: config{Channel: "#deploys,C123"}},
+		{name: "slack id", cfg: config{Channel: "@slack:U123ABC"}},
+		{name: "github login", cfg: config{Channel: "#deploys,@github:octocat", MappingEndpoint: "https://example.com/"}},
+		{name: "github login without mapping", cfg: config{Channel: "@github:octocat"}, expectErr: true},
+		{name: "empty login", cfg: config{Channel: "@github:", MappingEndpoint: "https://example.com/"}, expectErr: true},
+		{name: "invalid slack id", cfg: config{Channel: "@slack:alice"}, expectErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			err := validateDMTargets(tt.cfg)
+			if tt.expectErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestOpenDMChannels(t *testing.T) {
+	t.Parallel()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/octocat" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_, _ = w.Write([]byte(`{"slack_user_id":"U999"}`))
+	}))
+	t.Cleanup(srv.Close)
+
+	ctx := context.Background()
+	identities := &endpointResolver{client: srv.Client(), endpoint: srv.URL + "/"}
+
+	t.Run("opens each dm", func(t *testing.T) {
+		t.Parallel()
+		client := &fakeSlackClient{openChanID: "D123"}
+		cfg := config{Channel: "#deploys,@github:octocat,@slack:U123"}
+		dms, err := openDMChannels(ctx, cfg, identities, client)
+		require.NoError(t, err)
+		require.Equal(t, map[string]string{"@github:octocat": "D123", "@slack:U123": "D123"}, dms)
+	})
+
+	t.Run("no dm targets", func(t *testing.T) {
+		t.Parallel()
+		dms, err := openDMChannels(ctx, config{Channel: "#deploys"}, identities, &fakeSlackClient{})
+		require.NoError(t, err)
+		require.Nil(t, dms)
+	})
+
+	t.Run("unknown login", func(t *testing.T) {
+		t.Parallel()
+		_, err := openDMChannels(ctx, config{Channel: "@github:ghost"}, identities, &fakeSlackClient{})
+		var notFound *slackUserNotFoundError
+		require.ErrorAs(t, err, &notFound)
+	})
+
+	t.Run("open fails", func(t *testing.T) {
+		t.Parallel()
+		_, err := openDMChannels(ctx, config{Channel: "@slack:U123"}, identities, &fakeSlackClient{openErr: errors.New("user_not_found")})
+		require.ErrorContains(t, err, "user_not_found")
+	})
+}
+
+func TestExecuteDM(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	api := &flakySlackAPI{}
+	res := &result{}
+	cfg := config{
+		Channel:    "@github:octocat",
+		Message:    "<@U999> your deploy finished",
+		OutputDir:  dir,
+		dmChannels: map[string]string{"@github:octocat": "D123"},
+	}
+	require.NoError(t, execute(context.Background(), cfg, membershipModeInvite, api, res))
+
+	require.Equal(t, "D123", res.ChannelID)
+	require.Equal(t, "D123", readOutput(t, dir, "channel-id"))
+	require.Empty(t, api.invited, "nobody is invited into a DM")
+	require.Contains(t, readOutput(t, dir, "manifest.json"), `"channel_id": "D123"`)
+}
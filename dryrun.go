@@ -0,0 +1,125 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/slack-go/slack"
+)
+
+// Placeholder IDs returned in a dry run.
+const (
+	dryRunDMChannelID  = "D0DRYRUN"
+	dryRunFileID       = "F0DRYRUN"
+	dryRunScheduledID  = "Q0DRYRUN"
+	dryRunPermalinkURL = "https://slack.com/archives/"
+)
+
+// dryRunTransport stands in for the Slack API when SLACK_DRY_RUN is set. It
+// prints every request the run would make to out, with JSON payloads such as
+// blocks and attachments indented, and answers with a canned success so the
+// rest of the run carries on as if Slack had accepted it.
+type dryRunTransport struct {
+	out io.Writer
+
+	mu  sync.Mutex
+	seq int
+}
+
+// newDryRunClient returns a Slack client whose every call goes to a
+// dryRunTransport instead of Slack.
+func newDryRunClient(token string, out io.Writer) *slack.Client {
+	return slack.New(token, slack.OptionHTTPClient(&http.Client{Transport: &dryRunTransport{out: out}}))
+}
+
+func (t *dryRunTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	method, isAPI := strings.CutPrefix(req.URL.Path, "/api/")
+	if !isAPI {
+		// The file contents sent to an upload URL from files.getUploadURLExternal.
+		n, err := io.Copy(io.Discard, req.Body)
+		if err != nil {
+			return nil, err
+		}
+		fmt.Fprintf(t.out, "==> upload %d bytes to %s\n", n, req.URL)
+		return dryRunResponse(req, "OK"), nil
+	}
+
+	if err := req.ParseForm(); err != nil {
+		return nil, fmt.Errorf("dry run: parse %s request: %w", method, err)
+	}
+	t.print(method, req.Form)
+
+	t.seq++
+	ts := fmt.Sprintf("%d.%06d", time.Now().Unix(), t.seq)
+	channel := req.Form.Get("channel")
+
+	var body any
+	switch method {
+	case "chat.postMessage", "chat.postEphemeral", "chat.update", "chat.delete":
+		body = map[string]any{"ok": true, "channel": channel, "ts": firstNonEmpty(req.Form.Get("ts"), ts)}
+	case "chat.scheduleMessage":
+		body = map[string]any{"ok": true, "channel": channel, "scheduled_message_id": dryRunScheduledID}
+	case "chat.getPermalink":
+		body = map[string]any{"ok": true, "channel": channel, "permalink": dryRunPermalinkURL + channel + "/p" + strings.ReplaceAll(req.Form.Get("message_ts"), ".", "")}
+	case "conversations.open":
+		body = map[string]any{"ok": true, "channel": map[string]any{"id": dryRunDMChannelID}}
+	case "conversations.invite":
+		body = map[string]any{"ok": true, "channel": map[string]any{"id": channel}}
+	case "files.getUploadURLExternal":
+		body = map[string]any{"ok": true, "upload_url": "https://files.slack.com/upload/v1/dry-run", "file_id": dryRunFileID}
+	case "files.completeUploadExternal":
+		body = map[string]any{"ok": true, "files": []map[string]any{{"id": dryRunFileID}}}
+	case "users.lookupByEmail":
+		body = map[string]any{"ok": false, "error": "users_not_found"}
+	default:
+		// Lookups (conversations.list, conversations.members, usergroups.*)
+		// find nothing, so every mentioned user is acted on.
+		body = map[string]any{"ok": true}
+	}
+	data, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+	return dryRunResponse(req, string(data)), nil
+}
+
+// print writes one request: the method, then its parameters in order, without
+// the token.
+func (t *dryRunTransport) print(method string, form map[string][]string) {
+	fmt.Fprintf(t.out, "==> %s\n", method)
+	keys := make([]string, 0, len(form))
+	for k := range form {
+		if k != "token" {
+			keys = append(keys, k)
+		}
+	}
+	slices.Sort(keys)
+	for _, k := range keys {
+		for _, v := range form[k] {
+			var indented bytes.Buffer
+			if (strings.HasPrefix(v, "[") || strings.HasPrefix(v, "{")) && json.Indent(&indented, []byte(v), "    ", "  ") == nil {
+				v = indented.String()
+			}
+			fmt.Fprintf(t.out, "    %s: %s\n", k, v)
+		}
+	}
+}
+
+func dryRunResponse(req *http.Request, body string) *http.Response {
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Header:     http.Header{"Content-Type": {"application/json"}},
+		Body:       io.NopCloser(strings.NewReader(body)),
+		Request:    req,
+	}
+}
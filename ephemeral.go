@@ -0,0 +1,70 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"regexp"
+
+	"github.com/slack-go/slack"
+)
+
+// slackUserIDRe matches a Slack user ID as given in SLACK_EPHEMERAL_USER.
+// Anything else is taken to be a GitHub login.
+var slackUserIDRe = regexp.MustCompile(`^[UW][A-Z0-9]+$`)
+
+// validateEphemeral rejects combinations chat.postEphemeral can't do.
+// Ephemeral messages can't be updated, deleted or scheduled, and files
+// uploaded next to one would be visible to the whole channel.
+func validateEphemeral(cfg config) error {
+	if cfg.EphemeralUser == "" {
+		return nil
+	}
+	if cfg.UpdateTs != "" || cfg.DeleteTs != "" || cfg.ManifestFile != "" || cfg.PostAt != "" || cfg.DeleteScheduledID != "" || cfg.rootUpdate != nil || len(cfg.Files) > 0 {
+		return errors.New("SLACK_EPHEMERAL_USER can't be combined with updates, deletes, manifests, scheduling, root updates or file uploads")
+	}
+	if !slackUserIDRe.MatchString(cfg.EphemeralUser) && !hasIdentitySource(cfg) {
+		return fmt.Errorf("SLACK_EPHEMERAL_USER %q is not a Slack user ID and no GitHub-to-Slack mapping is configured to resolve it", cfg.EphemeralUser)
+	}
+	return nil
+}
+
+// resolveEphemeralUser returns the Slack user ID for SLACK_EPHEMERAL_USER,
+// looking GitHub logins up in the configured mapping.
+func resolveEphemeralUser(ctx context.Context, cfg config, identities identityResolver) (string, error) {
+	if slackUserIDRe.MatchString(cfg.EphemeralUser) {
+		return cfg.EphemeralUser, nil
+	}
+
+	slackID, err := identities.SlackUserID(ctx, cfg.EphemeralUser)
+	if err != nil {
+		return "", fmt.Errorf("resolve SLACK_EPHEMERAL_USER: %w", err)
+	}
+	slog.Info("Slack ID found", "github_user", cfg.EphemeralUser, "slack_user_id", slackID)
+	return slackID, nil
+}
+
+// ephemeralOptions builds the chat.postEphemeral call for the message.
+func ephemeralOptions(cfg config) []slack.MsgOption {
+	options := []slack.MsgOption{content(cfg), slack.MsgOptionPostEphemeral(cfg.ephemeralUserID)}
+	if cfg.ThreadTs != "" {
+		options = append(options, slack.MsgOptionTS(cfg.ThreadTs))
+	}
+	return options
+}
+
+// postEphemeral sends the message so only cfg.ephemeralUserID sees it. Slack
+// keeps no lasting copy, so there is no permalink and nothing to update later.
+func postEphemeral(ctx context.Context, cfg config, client slackAPI, res *result) error {
+	channelID, messageTs, _, err := client.SendMessageContext(ctx, cfg.Channel, ephemeralOptions(cfg)...)
+	if err != nil {
+		return fmt.Errorf("ephemeral message: %w", err)
+	}
+
+	// chat.postEphemeral doesn't echo the channel back.
+	channelID = firstNonEmpty(channelID, cfg.Channel)
+	slog.Info("Ephemeral message sent", "channel_id", channelID, "user", cfg.ephemeralUserID, "message_ts", messageTs)
+	res.ChannelID, res.MessageTs, res.ThreadTs = channelID, messageTs, cfg.ThreadTs
+	return nil
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateEphemeral(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name      string
+		cfg       config
+		expectErr bool
+	}{
+		{name: "unset", cfg: config{UpdateTs: "1.1"}},
+		{name: "slack id", cfg: config{EphemeralUser: "U123ABC"}},
+		{name: "github login with mapping", cfg: config{EphemeralUser: "octocat", MappingEndpoint: "https://example.com/"}},
+		{name: "github login without mapping", cfg: config{EphemeralUser: "octocat"}, expectErr: true},
+		{name: "update", cfg: config{EphemeralUser: "U123ABC", UpdateTs: "1.1"}, expectErr: true},
+		{name: "scheduled", cfg: config{EphemeralUser: "U123ABC", PostAt: "1h"}, expectErr: true},
+		{name: "files", cfg: config{EphemeralUser: "U123ABC", Files: []string{"*.log"}}, expectErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			err := validateEphemeral(tt.cfg)
+			if tt.expectErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestResolveEphemeralUser(t *testing.T) {
+	t.Parallel()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/octocat" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_, _ = w.Write([]byte(`{"slack_user_id":"U999"}`))
+	}))
+	t.Cleanup(srv.Close)
+
+	ctx := context.Background()
+
+	identities := &endpointResolver{client: srv.Client(), endpoint: srv.URL + "/"}
+
+	id, err := resolveEphemeralUser(ctx, config{EphemeralUser: "W123"}, nil)
+	require.NoError(t, err)
+	require.Equal(t, "W123", id)
+
+	id, err = resolveEphemeralUser(ctx, config{EphemeralUser: "octocat"}, identities)
+	require.NoError(t, err)
+	require.Equal(t, "U999", id)
+
+	_, err = resolveEphemeralUser(ctx, config{EphemeralUser: "ghost"}, identities)
+	var notFound *slackUserNotFoundError
+	require.ErrorAs(t, err, &notFound)
+}
+
+func TestEphemeralOptions(t *testing.T) {
+	t.Parallel()
+
+	values := capturePostMessage(t, ephemeralOptions(config{Message: "only you", ThreadTs: "111.222", ephemeralUserID: "U123"})...)
+	require.Equal(t, "U123", values.Get("user"))
+	require.Equal(t, "111.222", values.Get("thread_ts"))
+	require.Contains(t, values.Get("attachments"), "only you")
+}
+
+func TestExecuteEphemeral(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	api := &flakySlackAPI{}
+	res := &result{}
+	cfg := config{Channel: "C123", Message: "only you", EphemeralUser: "U123", ephemeralUserID: "U123", OutputDir: dir}
+	require.NoError(t, execute(context.Background(), cfg, membershipModeInvite, api, res))
+
+	require.Equal(t, 1, api.sendCalls)
+	require.Equal(t, "ephemeral", res.Operation)
+	require.Equal(t, "111.222", res.MessageTs)
+	require.Empty(t, res.Permalink)
+	require.Empty(t, api.invited)
+	require.Equal(t, "111.222", readOutput(t, dir, "message-ts"))
+	require.Empty(t, readOutput(t, dir, "thread-ts"))
+	require.NoFileExists(t, filepath.Join(dir, "manifest.json"))
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// splitChannels returns the channels listed in SLACK_CHANNEL, dropping blanks
+// and duplicates, including the same name with and without "#".
+func splitChannels(s string) []string {
+	seen := make(map[string]struct{})
+	var channels []string
+	for _, ch := range strings.Split(s, ",") {
+		ch = strings.TrimSpace(ch)
+		if ch == "" {
+			continue
+		}
+		// "#deploys" and "deploys" are the same channel and would share an
+		// output subdirectory, so the first one given wins.
+		dir := channelDirName(ch)
+		if _, ok := seen[dir]; ok {
+			continue
+		}
+		seen[dir] = struct{}{}
+		channels = append(channels, ch)
+	}
+	return channels
+}
+
+var unsafeDirChars = regexp.MustCompile(`[^A-Za-z0-9._-]`)
+
+// channelDirName turns a channel as given in SLACK_CHANNEL into the name of its
+// output subdirectory: "#deploys" becomes "deploys".
+func channelDirName(channel string) string {
+	return unsafeDirChars.ReplaceAllString(strings.TrimLeft(channel, "#"), "_")
+}
+
+// fanOutTarget is one message a fan-out sends, updates or deletes.
+type fanOutTarget struct {
+	// key names the target's entry in res.Channels and its output
+	// subdirectory: the channel as given in SLACK_CHANNEL or the manifest.
+	key      string
+	channel  string
+	threadTs string
+	updateTs string
+	deleteTs string
+}
+
+// apply returns cfg retargeted at t. Output locations are left to the caller.
+func (t fanOutTarget) apply(cfg config) config {
+	cfg.Channel = t.channel
+	cfg.ThreadTs = t.threadTs
+	cfg.UpdateTs = t.updateTs
+	cfg.DeleteTs = t.deleteTs
+	return cfg
+}
+
+// channelTargets returns one target per channel listed in SLACK_CHANNEL.
+func channelTargets(channels []string) []fanOutTarget {
+	targets := make([]fanOutTarget, len(channels))
+	for i, ch := range channels {
+		targets[i] = fanOutTarget{key: ch, channel: ch}
+	}
+	return targets
+}
+
+// fanOut runs the operation for every target, at most cfg.FanOutConcurrency at
+// a time. Each target gets its own outputs in a subdirectory of the output
+// directory and its own entry in res.Channels. A failure in one target doesn't
+// stop the others.
+func fanOut(ctx context.Context, cfg config, targets []fanOutTarget, mode membershipMode, slackClient slackAPI, res *result) error {
+	if len(targets) > 0 {
+		res.Operation = operation(targets[0].apply(cfg))
+	}
+	res.Channels = make(map[string]*result, len(targets))
+
+	var (
+		mu   sync.Mutex
+		wg   sync.WaitGroup
+		sem  = make(chan struct{}, max(cfg.FanOutConcurrency, 1))
+		errs = make([]error, len(targets))
+	)
+	for i, target := range targets {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			chCfg := target.apply(cfg)
+			chCfg.GitHubActions = false
+			if cfg.OutputDir != "" {
+				chCfg.OutputDir = filepath.Join(cfg.OutputDir, channelDirName(target.key))
+			}
+
+			chRes := &result{}
+			err := prepareOutputDir(chCfg.OutputDir)
+			if err == nil {
+				err = sendToChannel(ctx, chCfg, mode, slackClient, chRes)
+			}
+			if err != nil {
+				err = fmt.Errorf("channel %s: %w", target.key, err)
+				slog.Error("Failed to send to channel", "channel", target.key, "error", err)
+				chRes.setError(err)
+			}
+
+			mu.Lock()
+			res.Channels[target.key] = chRes
+			mu.Unlock()
+			errs[i] = err
+		}()
+	}
+	wg.Wait()
+
+	if cfg.GitHubActions {
+		if err := writeGitHubOutputs(cfg, res); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	// Exit with the code of the first failing target in order, not whichever
+	// error exitCodeFor happens to match first in the joined errors.
+	for _, err := range errs {
+		if err != nil {
+			return &exitError{code: exitCodeFor(err), err: errors.Join(errs...)}
+		}
+	}
+	return nil
+}
+
+func prepareOutputDir(dir string) error {
+	if dir == "" {
+		return nil
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return outputError(fmt.Errorf("create output directory: %w", err))
+	}
+	return nil
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/slack-go/slack"
+	"github.com/stretchr/testify/require"
+)
+
+// fanOutSlackAPI is a concurrency-safe slackAPI that resolves "#name" to the ID
+// "C-name", fails sends to the channels in failing and tracks the peak number of
+// concurrent sends.
+type fanOutSlackAPI struct {
+	flakySlackAPI
+	failing map[string]error
+
+	mu       sync.Mutex
+	inFlight int
+	peak     int
+	release  chan struct{}
+}
+
+func (f *fanOutSlackAPI) SendMessageContext(_ context.Context, channel string, _ ...slack.MsgOption) (string, string, string, error) {
+	f.mu.Lock()
+	f.inFlight++
+	f.peak = max(f.peak, f.inFlight)
+	f.mu.Unlock()
+
+	if f.release != nil {
+		<-f.release
+	}
+
+	f.mu.Lock()
+	f.inFlight--
+	f.mu.Unlock()
+
+	if err := f.failing[channel]; err != nil {
+		return "", "", "", err
+	}
+	return "C-" + channelDirName(channel), "111.222", "", nil
+}
+
+func TestSplitChannels(t *testing.T) {
+	t.Parallel()
+
+	require.Equal(t, []string{"C123"}, splitChannels("C123"))
+	require.Equal(t, []string{"#deploys", "#team"}, splitChannels(" #deploys, #team,,#deploys "))
+	require.Equal(t, []string{"deploys", "C123"}, splitChannels("deploys,#deploys,C123,##deploys"))
+	require.Nil(t, splitChannels(""))
+}
+
+func TestChannelDirName(t *testing.T) {
+	t.Parallel()
+
+	require.Equal(t, "deploys", channelDirName("#deploys"))
+	require.Equal(t, "C123", channelDirName("C123"))
+	require.Equal(t, "a_b", channelDirName("a/b"))
+}
+
+func TestFanOut(t *testing.T) {
+	t.Parallel()
+
+	t.Run("writes per-channel outputs", func(t *testing.T) {
+		t.Parallel()
+		dir := t.TempDir()
+		api := &fanOutSlackAPI{}
+		res := &result{}
+		cfg := config{Channel: "#deploys,#team", OutputDir: dir, FanOutConcurrency: 2}
+		require.NoError(t, execute(context.Background(), cfg, membershipModeNone, api, res))
+
+		require.Equal(t, "post", res.Operation)
+		require.Len(t, res.Channels, 2)
+		require.Equal(t, "C-deploys", res.Channels["#deploys"].ChannelID)
+		require.Equal(t, "C-team", readOutput(t, filepath.Join(dir, "team"), "channel-id"))
+		require.Equal(t, "111.222", readOutput(t, filepath.Join(dir, "deploys"), "message-ts"))
+		require.NoFileExists(t, filepath.Join(dir, "channel-id"))
+	})
+
+	t.Run("one failure doesn't stop the others", func(t *testing.T) {
+		t.Parallel()
+		dir := t.TempDir()
+		api := &fanOutSlackAPI{failing: map[string]error{"#gone": slack.SlackErrorResponse{Err: "channel_not_found"}}}
+		res := &result{}
+		cfg := config{Channel: "#gone,#team", OutputDir: dir, FanOutConcurrency: 1}
+		err := execute(context.Background(), cfg, membershipModeNone, api, res)
+		require.Equal(t, exitChannelNotFound, exitCodeFor(err))
+		require.Equal(t, exitChannelNotFound, res.Channels["#gone"].Error.ExitCode)
+		require.Nil(t, res.Channels["#team"].Error)
+		require.Equal(t, "C-team", readOutput(t, filepath.Join(dir, "team"), "channel-id"))
+
+		require.NoError(t, writeResult(dir, res))
+		var got struct {
+			Channels map[string]json.RawMessage `json:"channels"`
+		}
+		data, err := os.ReadFile(filepath.Join(dir, "result.json"))
+		require.NoError(t, err)
+		require.NoError(t, json.Unmarshal(data, &got))
+		require.Len(t, got.Channels, 2)
+	})
+
+	t.Run("exit code of the first failure", func(t *testing.T) {
+		t.Parallel()
+		dir := t.TempDir()
+		// A file where #team's output subdirectory should be fails it with exitOutputWrite.
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "team"), nil, 0644))
+		api := &fanOutSlackAPI{failing: map[string]error{"#limited": &slack.RateLimitedError{RetryAfter: time.Second}}}
+		cfg := config{Channel: "#limited,#team", OutputDir: dir, FanOutConcurrency: 2}
+		err := execute(context.Background(), cfg, membershipModeNone, api, &result{})
+		require.ErrorContains(t, err, "channel #team")
+		require.Equal(t, exitRateLimited, exitCodeFor(err))
+	})
+
+	t.Run("bounded parallelism", func(t *testing.T) {
+		t.Parallel()
+		api := &fanOutSlackAPI{release: make(chan struct{})}
+		done := make(chan error)
+		go func() {
+			cfg := config{Channel: "a,b,c,d,e", FanOutConcurrency: 2}
+			done <- execute(context.Background(), cfg, membershipModeNone, api, &result{})
+		}()
+		for range 5 {
+			api.release <- struct{}{}
+		}
+		require.NoError(t, <-done)
+		require.LessOrEqual(t, api.peak, 2)
+	})
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/slack-go/slack"
+)
+
+// slackFileClient is the subset of *slack.Client used to upload files. The
+// upload goes through Slack's external flow (getUploadURLExternal, upload,
+// completeUploadExternal), which UploadFileContext wraps in a single call.
+type slackFileClient interface {
+	UploadFileContext(ctx context.Context, params slack.UploadFileParameters) (*slack.FileSummary, error)
+}
+
+// expandFileGlobs resolves each pattern to the files it matches, preserving the
+// order of the patterns and dropping duplicates. A pattern that matches nothing
+// is logged and skipped rather than treated as an error, since build artifacts
+// are often optional.
+func expandFileGlobs(patterns []string) ([]string, error) {
+	seen := make(map[string]struct{})
+	var paths []string
+	for _, pattern := range patterns {
+		pattern = strings.TrimSpace(pattern)
+		if pattern == "" {
+			continue
+		}
+
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid SLACK_FILES pattern %q: %w", pattern, err)
+		}
+		if len(matches) == 0 {
+			slog.Warn("SLACK_FILES pattern matched no files", "pattern", pattern)
+			continue
+		}
+
+		for _, m := range matches {
+			if _, ok := seen[m]; ok {
+				continue
+			}
+			seen[m] = struct{}{}
+			paths = append(paths, m)
+		}
+	}
+	return paths, nil
+}
+
+// uploadFiles uploads every file matched by patterns to the channel (and
+// thread, if threadTs is set) and returns the Slack file IDs in upload order.
+// Directories and empty files are skipped, as Slack rejects zero-byte uploads.
+func uploadFiles(ctx context.Context, client slackFileClient, channelID, threadTs string, patterns []string) ([]string, error) {
+	paths, err := expandFileGlobs(patterns)
+	if err != nil {
+		return nil, err
+	}
+
+	var ids []string
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			return ids, fmt.Errorf("stat %s: %w", path, err)
+		}
+		if info.IsDir() {
+			slog.Warn("Skipping directory in SLACK_FILES", "path", path)
+			continue
+		}
+		if info.Size() == 0 {
+			slog.Warn("Skipping empty file in SLACK_FILES", "path", path)
+			continue
+		}
+
+		file, err := client.UploadFileContext(ctx, slack.UploadFileParameters{
+			File:            path,
+			FileSize:        int(info.Size()),
+			Filename:        filepath.Base(path),
+			Title:           filepath.Base(path),
+			Channel:         channelID,
+			ThreadTimestamp: threadTs,
+		})
+		if err != nil {
+			return ids, fmt.Errorf("upload %s: %w", path, err)
+		}
+
+		slog.Info("File uploaded", "path", path, "file_id", file.ID)
+		ids = append(ids, file.ID)
+	}
+	return ids, nil
+}
+
+// uploadOnly uploads the files without sending a message, for the upload
+// command. Unlike uploads next to a message, matching nothing is an error.
+func uploadOnly(ctx context.Context, cfg config, client slackFileClient, res *result) error {
+	ids, err := uploadFiles(ctx, client, cfg.Channel, cfg.ThreadTs, cfg.Files)
+	res.ChannelID, res.ThreadTs, res.FileIDs = cfg.Channel, cfg.ThreadTs, ids
+	if err != nil {
+		return err
+	}
+	if len(ids) == 0 {
+		return errors.New("SLACK_FILES matched no files to upload")
+	}
+	return nil
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/slack-go/slack"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeFileClient implements slackFileClient, recording the upload parameters and
+// handing out sequential file IDs.
+type fakeFileClient struct {
+	uploadErr error
+	uploads   []slack.UploadFileParameters
+}
+
+func (f *fakeFileClient) UploadFileContext(_ context.Context, params slack.UploadFileParameters) (*slack.FileSummary, error) {
+	if f.uploadErr != nil {
+		return nil, f.uploadErr
+	}
+	f.uploads = append(f.uploads, params)
+	return &slack.FileSummary{ID: "F" + params.Filename}, nil
+}
+
+func writeTestFile(t *testing.T, dir, name, content string) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+	return path
+}
+
+func TestExpandFileGlobs(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	a := writeTestFile(t, dir, "a.log", "a")
+	b := writeTestFile(t, dir, "b.log", "b")
+	c := writeTestFile(t, dir, "c.txt", "c")
+
+	tests := []struct {
+		name      string
+		patterns  []string
+		expected  []string
+		expectErr bool
+	}{
+		{name: "single file", patterns: []string{c}, expected: []string{c}},
+		{name: "glob", patterns: []string{filepath.Join(dir, "*.log")}, expected: []string{a, b}},
+		{name: "dedup across patterns", patterns: []string{a, filepath.Join(dir, "*.log")}, expected: []string{a, b}},
+		{name: "no match is skipped", patterns: []string{filepath.Join(dir, "*.png"), c}, expected: []string{c}},
+		{name: "blank entries ignored", patterns: []string{" ", c}, expected: []string{c}},
+		{name: "bad pattern errors", patterns: []string{"["}, expectErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			got, err := expandFileGlobs(tt.patterns)
+			if tt.expectErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.expected, got)
+		})
+	}
+}
+
+func TestUploadFiles(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	writeTestFile(t, dir, "report.xml", "<ok/>")
+	writeTestFile(t, dir, "empty.log", "")
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "sub"), 0755))
+
+	t.Run("uploads to channel and thread", func(t *testing.T) {
+		t.Parallel()
+		client := &fakeFileClient{}
+		ids, err := uploadFiles(context.Background(), client, "C123", "111.222", []string{filepath.Join(dir, "*")})
+		require.NoError(t, err)
+		require.Equal(t, []string{"Freport.xml"}, ids)
+		require.Len(t, client.uploads, 1)
+		require.Equal(t, "C123", client.uploads[0].Channel)
+		require.Equal(t, "111.222", client.uploads[0].ThreadTimestamp)
+		require.Equal(t, 5, client.uploads[0].FileSize)
+	})
+
+	t.Run("upload error is returned", func(t *testing.T) {
+		t.Parallel()
+		client := &fakeFileClient{uploadErr: errors.New("not_in_channel")}
+		_, err := uploadFiles(context.Background(), client, "C123", "", []string{filepath.Join(dir, "report.xml")})
+		require.Error(t, err)
+	})
+}
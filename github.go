@@ -0,0 +1,100 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"os"
+	"reflect"
+	"slices"
+	"strings"
+)
+
+// applyInputAliases lets a GitHub Actions Docker container action set any
+// config field through its inputs. The runner exposes each input as INPUT_<NAME>,
+// so SLACK_THREAD_TS can be given as the thread_ts input and GH_USER as gh_user.
+// Variables set explicitly always win, and empty inputs are ignored because the
+// runner exports every declared input, set or not.
+func applyInputAliases() error {
+	t := reflect.TypeOf(config{})
+	for i := range t.NumField() {
+		key := t.Field(i).Tag.Get("envconfig")
+		if key == "" {
+			continue
+		}
+		if _, ok := os.LookupEnv(key); ok {
+			continue
+		}
+
+		alias := "INPUT_" + strings.TrimPrefix(key, "SLACK_")
+		if v := os.Getenv(alias); v != "" {
+			if err := os.Setenv(key, v); err != nil {
+				return fmt.Errorf("set %s from %s: %w", key, alias, err)
+			}
+		}
+	}
+	return nil
+}
+
+// writeGitHubOutputs appends the run's IDs to $GITHUB_OUTPUT as step outputs
+// and a link to the message to $GITHUB_STEP_SUMMARY. Either file is skipped if
+// the runner didn't provide it.
+func writeGitHubOutputs(cfg config, res *result) error {
+	if cfg.GitHubOutput != "" {
+		var rootMessageTs string
+		if res.RootUpdate != nil {
+			rootMessageTs = res.RootUpdate.MessageTs
+		}
+		var b strings.Builder
+		for _, kv := range [][2]string{
+			{"channel-id", res.ChannelID},
+			{"message-ts", res.MessageTs},
+			{"thread-ts", res.ThreadTs},
+			{"permalink", res.Permalink},
+			{"thread-permalink", res.ThreadPermalink},
+			{"file-ids", strings.Join(res.FileIDs, ",")},
+			{"scheduled-message-id", res.ScheduledMessageID},
+			{"root-message-ts", rootMessageTs},
+		} {
+			fmt.Fprintf(&b, "%s=%s\n", kv[0], kv[1])
+		}
+		if len(res.Channels) > 0 {
+			channels, err := json.Marshal(res.Channels)
+			if err != nil {
+				return outputError(fmt.Errorf("marshal channel results: %w", err))
+			}
+			fmt.Fprintf(&b, "channels=%s\n", channels)
+		}
+		if err := appendFile(cfg.GitHubOutput, b.String()); err != nil {
+			return outputError(fmt.Errorf("write GITHUB_OUTPUT: %w", err))
+		}
+	}
+
+	if cfg.GitHubStepSummary != "" {
+		var summary strings.Builder
+		if res.Permalink != "" {
+			fmt.Fprintf(&summary, "Slack message (%s): %s\n", res.Operation, res.Permalink)
+		}
+		for _, channel := range slices.Sorted(maps.Keys(res.Channels)) {
+			if link := res.Channels[channel].Permalink; link != "" {
+				fmt.Fprintf(&summary, "- Slack message in %s (%s): %s\n", channel, res.Operation, link)
+			}
+		}
+		if err := appendFile(cfg.GitHubStepSummary, summary.String()); err != nil {
+			return outputError(fmt.Errorf("write GITHUB_STEP_SUMMARY: %w", err))
+		}
+	}
+	return nil
+}
+
+func appendFile(path, s string) error {
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.WriteString(s); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
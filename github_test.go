@@ -0,0 +1,63 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestApplyInputAliases(t *testing.T) {
+	t.Setenv("INPUT_CHANNEL", "#deploys")
+	t.Setenv("INPUT_THREAD_TS", "111.222")
+	t.Setenv("INPUT_GH_USER", "octocat")
+	t.Setenv("INPUT_TITLE", "")
+	t.Setenv("SLACK_MESSAGE", "explicit")
+	t.Setenv("INPUT_MESSAGE", "from input")
+	// Unset the targets so the aliases can fill them; t.Setenv restores them.
+	for _, key := range []string{"SLACK_CHANNEL", "SLACK_THREAD_TS", "GH_USER", "SLACK_TITLE"} {
+		t.Setenv(key, "")
+		require.NoError(t, os.Unsetenv(key))
+	}
+
+	require.NoError(t, applyInputAliases())
+
+	require.Equal(t, "#deploys", os.Getenv("SLACK_CHANNEL"))
+	require.Equal(t, "111.222", os.Getenv("SLACK_THREAD_TS"))
+	require.Equal(t, "octocat", os.Getenv("GH_USER"))
+	require.Equal(t, "explicit", os.Getenv("SLACK_MESSAGE"))
+	_, ok := os.LookupEnv("SLACK_TITLE")
+	require.False(t, ok, "empty inputs must not set the variable")
+}
+
+func TestWriteGitHubOutputs(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	outputPath := filepath.Join(dir, "output")
+	summaryPath := filepath.Join(dir, "summary")
+	require.NoError(t, os.WriteFile(outputPath, []byte("existing=1\n"), 0644))
+
+	cfg := config{GitHubOutput: outputPath, GitHubStepSummary: summaryPath}
+	res := &result{
+		Operation:       "post",
+		ChannelID:       "C123",
+		MessageTs:       "111.222",
+		ThreadTs:        "111.222",
+		Permalink:       "https://example.slack.com/archives/C123/p111222",
+		ThreadPermalink: "https://example.slack.com/archives/C123/p111222",
+	}
+	require.NoError(t, writeGitHubOutputs(cfg, res))
+
+	require.Equal(t, "existing=1\n"+
+		"channel-id=C123\n"+
+		"message-ts=111.222\n"+
+		"thread-ts=111.222\n"+
+		"permalink=https://example.slack.com/archives/C123/p111222\n"+
+		"thread-permalink=https://example.slack.com/archives/C123/p111222\n"+
+		"file-ids=\n"+
+		"scheduled-message-id=\n"+
+		"root-message-ts=\n", readOutput(t, dir, "output"))
+	require.Equal(t, "Slack message (post): https://example.slack.com/archives/C123/p111222\n", readOutput(t, dir, "summary"))
+}
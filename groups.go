@@ -0,0 +1,91 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+
+	"github.com/slack-go/slack"
+)
+
+// slackUserGroupClient is the subset of *slack.Client used to mention user
+// groups and list their members.
+type slackUserGroupClient interface {
+	GetUserGroupsContext(ctx context.Context, options ...slack.GetUserGroupsOption) ([]slack.UserGroup, error)
+	GetUserGroupMembersContext(ctx context.Context, userGroup string, options ...slack.GetUserGroupMembersOption) ([]string, error)
+}
+
+// resolveUserGroups returns the user groups named by handles, with or without
+// the leading "@", in the order given. Unknown handles are logged and skipped.
+func resolveUserGroups(ctx context.Context, client slackUserGroupClient, handles []string) ([]slack.UserGroup, error) {
+	all, err := client.GetUserGroupsContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	byHandle := make(map[string]slack.UserGroup, len(all))
+	for _, g := range all {
+		byHandle[strings.ToLower(g.Handle)] = g
+	}
+
+	var groups []slack.UserGroup
+	for _, handle := range handles {
+		handle = strings.TrimPrefix(strings.TrimSpace(handle), "@")
+		if handle == "" {
+			continue
+		}
+		g, ok := byHandle[strings.ToLower(handle)]
+		if !ok {
+			slog.Warn("User group not found, skipping mention", "handle", handle)
+			continue
+		}
+		groups = append(groups, g)
+	}
+	return groups, nil
+}
+
+// userGroupMembers lists the members of every group, for the membership step.
+// Groups whose members can't be listed are logged and skipped.
+func userGroupMembers(ctx context.Context, client slackUserGroupClient, groups []slack.UserGroup) []string {
+	var members []string
+	for _, g := range groups {
+		ids, err := client.GetUserGroupMembersContext(ctx, g.ID)
+		if err != nil {
+			slog.Warn("Failed to list user group members", "handle", g.Handle, "error", err)
+			continue
+		}
+		members = append(members, ids...)
+	}
+	return members
+}
+
+// mentionUserGroups prepends a <!subteam^ID> mention of every group in
+// SLACK_MENTION_GROUPS to the message. With SLACK_MENTION_GROUPS_EXPAND it also
+// returns the groups' members, so the membership mode applies to them. Like
+// user mentions, it is best-effort: failures are logged, never fatal.
+func mentionUserGroups(ctx context.Context, cfg config, mode membershipMode, client slackUserGroupClient) (string, []string) {
+	message := cfg.Message
+	if len(cfg.MentionGroups) == 0 {
+		return message, nil
+	}
+
+	groups, err := resolveUserGroups(ctx, client, cfg.MentionGroups)
+	if err != nil {
+		slog.Error("Failed to list user groups, skipping group mentions", "error", err)
+		return message, nil
+	}
+	if len(groups) == 0 {
+		return message, nil
+	}
+
+	mentions := make([]string, len(groups))
+	for i, g := range groups {
+		mentions[i] = "<!subteam^" + g.ID + ">"
+		slog.Info("User group found", "handle", g.Handle, "usergroup_id", g.ID)
+	}
+	message = strings.Join(mentions, " ") + " " + message
+
+	if !cfg.MentionGroupsExpand || mode == membershipModeNone {
+		return message, nil
+	}
+	return message, userGroupMembers(ctx, client, groups)
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/slack-go/slack"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeUserGroupClient serves a fixed list of user groups and their members.
+type fakeUserGroupClient struct {
+	groups     []slack.UserGroup
+	listErr    error
+	members    map[string][]string
+	membersErr error
+	listed     []string // group IDs whose members were listed
+}
+
+func (f *fakeUserGroupClient) GetUserGroupsContext(_ context.Context, _ ...slack.GetUserGroupsOption) ([]slack.UserGroup, error) {
+	return f.groups, f.listErr
+}
+
+func (f *fakeUserGroupClient) GetUserGroupMembersContext(_ context.Context, userGroup string, _ ...slack.GetUserGroupMembersOption) ([]string, error) {
+	f.listed = append(f.listed, userGroup)
+	if f.membersErr != nil {
+		return nil, f.membersErr
+	}
+	return f.members[userGroup], nil
+}
+
+func TestMentionUserGroups(t *testing.T) {
+	t.Parallel()
+
+	groups := []slack.UserGroup{
+		{ID: "S1", Handle: "platform-oncall"},
+		{ID: "S2", Handle: "Platform"},
+	}
+	members := map[string][]string{"S1": {"U1", "U2"}, "S2": {"U2", "U3"}}
+
+	tests := []struct {
+		name        string
+		cfg         config
+		mode        membershipMode
+		client      *fakeUserGroupClient
+		expected    string
+		wantMembers []string
+		wantListed  []string
+	}{
+		{
+			name:     "no groups",
+			cfg:      config{},
+			client:   &fakeUserGroupClient{groups: groups},
+			expected: "deploy failed",
+		},
+		{
+			name:     "handles with and without @",
+			cfg:      config{MentionGroups: []string{"@platform-oncall", "platform"}},
+			client:   &fakeUserGroupClient{groups: groups},
+			expected: "<!subteam^S1> <!subteam^S2> deploy failed",
+		},
+		{
+			name:     "unknown handle skipped",
+			cfg:      config{MentionGroups: []string{"ghosts", "platform-oncall"}},
+			client:   &fakeUserGroupClient{groups: groups},
+			expected: "<!subteam^S1> deploy failed",
+		},
+		{
+			name:     "list fails",
+			cfg:      config{MentionGroups: []string{"platform"}},
+			client:   &fakeUserGroupClient{listErr: errors.New("missing_scope")},
+			expected: "deploy failed",
+		},
+		{
+			name:        "expanded for invite",
+			cfg:         config{MentionGroups: []string{"platform-oncall", "platform"}, MentionGroupsExpand: true},
+			mode:        membershipModeInvite,
+			client:      &fakeUserGroupClient{groups: groups, members: members},
+			expected:    "<!subteam^S1> <!subteam^S2> deploy failed",
+			wantMembers: []string{"U1", "U2", "U2", "U3"},
+			wantListed:  []string{"S1", "S2"},
+		},
+		{
+			name:     "not expanded without a membership mode",
+			cfg:      config{MentionGroups: []string{"platform"}, MentionGroupsExpand: true},
+			mode:     membershipModeNone,
+			client:   &fakeUserGroupClient{groups: groups, members: members},
+			expected: "<!subteam^S2> deploy failed",
+		},
+		{
+			name:       "members fail",
+			cfg:        config{MentionGroups: []string{"platform"}, MentionGroupsExpand: true},
+			mode:       membershipModeNotify,
+			client:     &fakeUserGroupClient{groups: groups, membersErr: errors.New("boom")},
+			expected:   "<!subteam^S2> deploy failed",
+			wantListed: []string{"S2"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			cfg := tt.cfg
+			cfg.Message = "deploy failed"
+			got, members := mentionUserGroups(context.Background(), cfg, tt.mode, tt.client)
+			require.Equal(t, tt.expected, got)
+			require.Equal(t, tt.wantMembers, members)
+			require.Equal(t, tt.wantListed, tt.client.listed)
+		})
+	}
+}
+
+func TestEnsureMentionMembershipGroupMembers(t *testing.T) {
+	t.Parallel()
+
+	client := &fakeSlackClient{}
+	ensureMentionMembership(context.Background(), client, membershipModeInvite, mentionNotice{ChannelID: "C123", Message: "<!subteam^S1> hi <@U1>"}, "U1", "U2", "U2")
+	require.Equal(t, []string{"U1", "U2"}, client.invited)
+}
@@ -0,0 +1,204 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/slack-go/slack"
+	"gopkg.in/yaml.v3"
+)
+
+// identityResolver maps a GitHub login to a Slack user ID. It returns a
+// *slackUserNotFoundError when it has no mapping for the login.
+type identityResolver interface {
+	SlackUserID(ctx context.Context, ghUser string) (string, error)
+}
+
+// slackUserLookup is the subset of *slack.Client used to find users by email.
+type slackUserLookup interface {
+	GetUserByEmailContext(ctx context.Context, email string) (*slack.User, error)
+}
+
+// Identity sources, as listed in SLACK_IDENTITY_SOURCES.
+const (
+	identitySourceEndpoint = "endpoint"
+	identitySourceFile     = "file"
+	identitySourceEmail    = "email"
+)
+
+// identitySources returns the sources to try, in order. Without
+// SLACK_IDENTITY_SOURCES every configured source is tried, endpoint first.
+func identitySources(cfg config) ([]string, error) {
+	configured := map[string]bool{
+		identitySourceEndpoint: cfg.MappingEndpoint != "",
+		identitySourceFile:     cfg.IdentityMappingFile != "",
+		identitySourceEmail:    cfg.IdentityEmailFile != "",
+	}
+
+	if len(cfg.IdentitySources) == 0 {
+		var sources []string
+		for _, s := range []string{identitySourceEndpoint, identitySourceFile, identitySourceEmail} {
+			if configured[s] {
+				sources = append(sources, s)
+			}
+		}
+		return sources, nil
+	}
+
+	sources := make([]string, 0, len(cfg.IdentitySources))
+	for _, s := range cfg.IdentitySources {
+		s = strings.TrimSpace(s)
+		ok, known := configured[s]
+		switch {
+		case !known:
+			return nil, fmt.Errorf("invalid SLACK_IDENTITY_SOURCES entry %q (valid: endpoint, file, email)", s)
+		case !ok:
+			return nil, fmt.Errorf("SLACK_IDENTITY_SOURCES lists %s, which is not configured", s)
+		}
+		sources = append(sources, s)
+	}
+	return sources, nil
+}
+
+// hasIdentitySource reports whether GitHub logins can be resolved at all.
+func hasIdentitySource(cfg config) bool {
+	sources, err := identitySources(cfg)
+	return err == nil && len(sources) > 0
+}
+
+// newIdentityResolver chains the configured sources, or returns nil when there
+// are none.
+func newIdentityResolver(cfg config, httpClient *http.Client, users slackUserLookup) (identityResolver, error) {
+	sources, err := identitySources(cfg)
+	if err != nil || len(sources) == 0 {
+		return nil, err
+	}
+
+	chain := make(identityChain, 0, len(sources))
+	for _, s := range sources {
+		switch s {
+		case identitySourceEndpoint:
+			r, err := newEndpointResolver(cfg, httpClient)
+			if err != nil {
+				return nil, err
+			}
+			chain = append(chain, r)
+		case identitySourceFile:
+			ids, err := loadIdentityFile(cfg.IdentityMappingFile, slackUserIDRe.MatchString)
+			if err != nil {
+				return nil, fmt.Errorf("load SLACK_IDENTITY_MAPPING_FILE: %w", err)
+			}
+			chain = append(chain, staticResolver(ids))
+		case identitySourceEmail:
+			emails, err := loadIdentityFile(cfg.IdentityEmailFile, func(s string) bool { return strings.Contains(s, "@") })
+			if err != nil {
+				return nil, fmt.Errorf("load SLACK_IDENTITY_EMAIL_FILE: %w", err)
+			}
+			chain = append(chain, &emailResolver{users: users, emails: emails})
+		}
+	}
+	return chain, nil
+}
+
+// identityChain tries each resolver in turn; the first hit wins. A failing
+// source doesn't stop the later ones from being tried.
+type identityChain []identityResolver
+
+func (c identityChain) SlackUserID(ctx context.Context, ghUser string) (string, error) {
+	var errs []error
+	for _, r := range c {
+		id, err := r.SlackUserID(ctx, ghUser)
+		if err == nil {
+			return id, nil
+		}
+		var notFoundErr *slackUserNotFoundError
+		if !errors.As(err, &notFoundErr) {
+			errs = append(errs, err)
+		}
+	}
+	if len(errs) > 0 {
+		return "", errors.Join(errs...)
+	}
+	return "", &slackUserNotFoundError{GitHubUser: ghUser}
+}
+
+// staticResolver maps lowercased GitHub logins to Slack user IDs.
+type staticResolver map[string]string
+
+func (r staticResolver) SlackUserID(_ context.Context, ghUser string) (string, error) {
+	id, ok := r[strings.ToLower(ghUser)]
+	if !ok {
+		return "", &slackUserNotFoundError{GitHubUser: ghUser}
+	}
+	return id, nil
+}
+
+// emailResolver finds users with users.lookupByEmail, which needs the
+// users:read.email scope.
+type emailResolver struct {
+	users  slackUserLookup
+	emails map[string]string
+}
+
+func (r *emailResolver) SlackUserID(ctx context.Context, ghUser string) (string, error) {
+	email, ok := r.emails[strings.ToLower(ghUser)]
+	if !ok {
+		return "", &slackUserNotFoundError{GitHubUser: ghUser}
+	}
+
+	user, err := r.users.GetUserByEmailContext(ctx, email)
+	if err != nil {
+		if err.Error() == "users_not_found" {
+			return "", &slackUserNotFoundError{GitHubUser: ghUser}
+		}
+		return "", fmt.Errorf("look up Slack user by email: %w", err)
+	}
+	return user.ID, nil
+}
+
+// loadIdentityFile reads a GitHub login to value mapping, keyed by lowercased
+// login. ".csv" files hold "login,value" rows, with an optional header; any
+// other file is a YAML (or JSON) object.
+func loadIdentityFile(path string, valid func(string) bool) (map[string]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	raw := make(map[string]string)
+	if strings.EqualFold(filepath.Ext(path), ".csv") {
+		r := csv.NewReader(bytes.NewReader(data))
+		r.FieldsPerRecord = 2
+		r.Comment = '#'
+		r.TrimLeadingSpace = true
+		records, err := r.ReadAll()
+		if err != nil {
+			return nil, err
+		}
+		if len(records) > 0 && !valid(strings.TrimSpace(records[0][1])) {
+			records = records[1:]
+		}
+		for _, rec := range records {
+			raw[rec[0]] = rec[1]
+		}
+	} else if err := yaml.Unmarshal(data, &raw); err != nil {
+		return nil, err
+	}
+
+	ids := make(map[string]string, len(raw))
+	for login, value := range raw {
+		login, value = strings.TrimSpace(login), strings.TrimSpace(value)
+		if !valid(value) {
+			return nil, fmt.Errorf("invalid value %q for %s", value, login)
+		}
+		ids[strings.ToLower(login)] = value
+	}
+	return ids, nil
+}
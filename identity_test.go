@@ -0,0 +1,220 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"path/filepath"
+	"testing"
+
+	"github.com/slack-go/slack"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeUserLookup finds users by email in a fixed directory.
+type fakeUserLookup struct {
+	ids   map[string]string
+	err   error
+	calls int
+}
+
+func (f *fakeUserLookup) GetUserByEmailContext(_ context.Context, email string) (*slack.User, error) {
+	f.calls++
+	if f.err != nil {
+		return nil, f.err
+	}
+	id, ok := f.ids[email]
+	if !ok {
+		return nil, errors.New("users_not_found")
+	}
+	return &slack.User{ID: id}, nil
+}
+
+// fakeIdentityResolver answers from a map, or fails with err.
+type fakeIdentityResolver struct {
+	ids map[string]string
+	err error
+}
+
+func (f fakeIdentityResolver) SlackUserID(_ context.Context, ghUser string) (string, error) {
+	if f.err != nil {
+		return "", f.err
+	}
+	if id, ok := f.ids[ghUser]; ok {
+		return id, nil
+	}
+	return "", &slackUserNotFoundError{GitHubUser: ghUser}
+}
+
+func TestIdentitySources(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name      string
+		cfg       config
+		expected  []string
+		expectErr bool
+	}{
+		{name: "none", cfg: config{}},
+		{name: "endpoint only", cfg: config{MappingEndpoint: "https://example.com/"}, expected: []string{"endpoint"}},
+		{
+			name:     "default order",
+			cfg:      config{MappingEndpoint: "https://example.com/", IdentityMappingFile: "users.yaml", IdentityEmailFile: "emails.csv"},
+			expected: []string{"endpoint", "file", "email"},
+		},
+		{
+			name:     "explicit order",
+			cfg:      config{IdentitySources: []string{"file", " endpoint"}, MappingEndpoint: "https://example.com/", IdentityMappingFile: "users.yaml"},
+			expected: []string{"file", "endpoint"},
+		},
+		{name: "unknown", cfg: config{IdentitySources: []string{"ldap"}}, expectErr: true},
+		{name: "not configured", cfg: config{IdentitySources: []string{"email"}}, expectErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			got, err := identitySources(tt.cfg)
+			if tt.expectErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.expected, got)
+		})
+	}
+}
+
+func TestLoadIdentityFile(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	valid := slackUserIDRe.MatchString
+
+	tests := []struct {
+		name      string
+		file      string
+		content   string
+		expected  map[string]string
+		expectErr bool
+	}{
+		{
+			name:     "yaml",
+			file:     "users.yaml",
+			content:  "OctoCat: U1\nhubot: U2\n",
+			expected: map[string]string{"octocat": "U1", "hubot": "U2"},
+		},
+		{
+			name:     "json",
+			file:     "users.json",
+			content:  `{"octocat": "U1"}`,
+			expected: map[string]string{"octocat": "U1"},
+		},
+		{
+			name:     "csv with header",
+			file:     "users.csv",
+			content:  "github,slack\n# bots\nocto cat,U1\nhubot, U2\n",
+			expected: map[string]string{"octo cat": "U1", "hubot": "U2"},
+		},
+		{
+			name:     "csv without header",
+			file:     "users.CSV",
+			content:  "octocat,U1\n",
+			expected: map[string]string{"octocat": "U1"},
+		},
+		{name: "invalid value", file: "bad.yaml", content: "octocat: alice\n", expectErr: true},
+		{name: "wrong column count", file: "bad.csv", content: "octocat,U1,extra\n", expectErr: true},
+		{name: "missing", file: "missing.yaml", expectErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			path := filepath.Join(dir, tt.file)
+			if tt.content != "" {
+				path = writeTestFile(t, dir, tt.file, tt.content)
+			}
+			got, err := loadIdentityFile(path, valid)
+			if tt.expectErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.expected, got)
+		})
+	}
+}
+
+func TestIdentityChain(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	first := fakeIdentityResolver{ids: map[string]string{"octocat": "U1"}}
+	second := fakeIdentityResolver{ids: map[string]string{"octocat": "U9", "hubot": "U2"}}
+	broken := fakeIdentityResolver{err: errors.New("boom")}
+
+	id, err := identityChain{first, second}.SlackUserID(ctx, "octocat")
+	require.NoError(t, err)
+	require.Equal(t, "U1", id, "first hit wins")
+
+	id, err = identityChain{broken, first, second}.SlackUserID(ctx, "hubot")
+	require.NoError(t, err)
+	require.Equal(t, "U2", id, "a failing source doesn't stop later ones")
+
+	_, err = identityChain{first, second}.SlackUserID(ctx, "ghost")
+	var notFound *slackUserNotFoundError
+	require.ErrorAs(t, err, &notFound)
+
+	_, err = identityChain{first, broken}.SlackUserID(ctx, "ghost")
+	require.ErrorContains(t, err, "boom")
+	require.False(t, errors.As(err, &notFound))
+}
+
+func TestEmailResolver(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	users := &fakeUserLookup{ids: map[string]string{"octocat@example.com": "U1"}}
+	r := &emailResolver{users: users, emails: map[string]string{"octocat": "octocat@example.com", "hubot": "hubot@example.com"}}
+
+	id, err := r.SlackUserID(ctx, "OctoCat")
+	require.NoError(t, err)
+	require.Equal(t, "U1", id)
+
+	var notFound *slackUserNotFoundError
+	_, err = r.SlackUserID(ctx, "hubot")
+	require.ErrorAs(t, err, &notFound, "unknown email")
+	_, err = r.SlackUserID(ctx, "ghost")
+	require.ErrorAs(t, err, &notFound, "no email on file")
+	require.Equal(t, 2, users.calls)
+
+	r.users = &fakeUserLookup{err: slack.SlackErrorResponse{Err: "missing_scope"}}
+	_, err = r.SlackUserID(ctx, "octocat")
+	require.Equal(t, exitAuth, exitCodeFor(err))
+}
+
+func TestNewIdentityResolver(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	cfg := config{
+		IdentitySources:     []string{"file", "email"},
+		IdentityMappingFile: writeTestFile(t, dir, "users.yaml", "octocat: U1\n"),
+		IdentityEmailFile:   writeTestFile(t, dir, "emails.csv", "hubot,hubot@example.com\n"),
+	}
+	identities, err := newIdentityResolver(cfg, http.DefaultClient, &fakeUserLookup{ids: map[string]string{"hubot@example.com": "U2"}})
+	require.NoError(t, err)
+
+	for login, want := range map[string]string{"octocat": "U1", "hubot": "U2"} {
+		id, err := identities.SlackUserID(context.Background(), login)
+		require.NoError(t, err)
+		require.Equal(t, want, id)
+	}
+
+	identities, err = newIdentityResolver(config{}, http.DefaultClient, nil)
+	require.NoError(t, err)
+	require.Nil(t, identities)
+
+	_, err = newIdentityResolver(config{IdentityMappingFile: writeTestFile(t, dir, "bad.yaml", "octocat: nope\n")}, http.DefaultClient, nil)
+	require.Error(t, err)
+}
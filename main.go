@@ -4,13 +4,16 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"flag"
 	"fmt"
-	"io"
 	"log/slog"
 	"net/http"
 	"os"
 	"path/filepath"
 	"regexp"
+	"slices"
+	"strings"
+	"text/template"
 	"time"
 
 	"github.com/kelseyhightower/envconfig"
@@ -19,26 +22,150 @@ import (
 
 type config struct {
 	// Content
-	Color   string `envconfig:"SLACK_COLOR" default:"#008000"`
+	Color   string `envconfig:"SLACK_COLOR"`
 	Title   string `envconfig:"SLACK_TITLE"`
 	Message string `envconfig:"SLACK_MESSAGE"`
 	Context string `envconfig:"SLACK_CONTEXT"`
 
+	// Status picks a preset color, title emoji and fallback text (success,
+	// failure, warning, cancelled, in_progress, skipped). StatusPresetsFile
+	// overrides or extends the presets. Color defaults to green without one.
+	Status            string `envconfig:"SLACK_STATUS"`
+	StatusPresetsFile string `envconfig:"SLACK_STATUS_PRESETS_FILE"`
+	fallback          string
+
+	// Blocks and BlocksFile provide a raw Block Kit payload that replaces the
+	// title/message/context blocks. Title and Message remain the fallback text.
+	Blocks             string `envconfig:"SLACK_BLOCKS"`
+	BlocksFile         string `envconfig:"SLACK_BLOCKS_FILE"`
+	BlocksInAttachment bool   `envconfig:"SLACK_BLOCKS_IN_ATTACHMENT" default:"false"`
+	blocks             []slack.Block
+
+	// Template enables rendering Title, Message and Context as Go templates,
+	// with TemplateDataFile optionally providing JSON data as {{ .Data }}. It is
+	// off by default, since messages often carry untrusted text.
+	Template         bool   `envconfig:"SLACK_TEMPLATE" default:"false"`
+	TemplateDataFile string `envconfig:"SLACK_TEMPLATE_DATA_FILE"`
+
 	AlsoSendToChannel bool   `envconfig:"SLACK_ALSO_SEND_TO_CHANNEL" default:"false"`
-	Channel           string `envconfig:"SLACK_CHANNEL" required:"true"`
+	Channel           string `envconfig:"SLACK_CHANNEL"`
 	OutputDir         string `envconfig:"SLACK_OUTPUT_DIR" default:"/app/outputs"`
 	ThreadTs          string `envconfig:"SLACK_THREAD_TS"`
 	UpdateTs          string `envconfig:"SLACK_UPDATE_MESSAGE_TS"`
 	DeleteTs          string `envconfig:"SLACK_DELETE_MESSAGE_TS"`
-	Token             string `envconfig:"SLACK_TOKEN" required:"true"`
+	Token             string `envconfig:"SLACK_TOKEN"`
 	GitHubUser        string `envconfig:"GH_USER"`
 	EnableMentions    bool   `envconfig:"ENABLE_SLACK_MENTIONS"`
 	MappingEndpoint   string `envconfig:"GITHUB_SLACK_MAPPING_ENDPOINT"`
 
+	// MappingEndpoint may contain {login} or {login_lower}; otherwise the login
+	// is appended. MappingHeaders are extra "Name: value" request headers and
+	// MappingTokenFile holds a bearer token for it. MappingSelector is the path
+	// to the Slack user ID in the JSON response, e.g. "user.slack".
+	MappingHeaders   []string `envconfig:"GITHUB_SLACK_MAPPING_HEADERS"`
+	MappingTokenFile string   `envconfig:"GITHUB_SLACK_MAPPING_TOKEN_FILE"`
+	MappingSelector  string   `envconfig:"GITHUB_SLACK_MAPPING_SELECTOR" default:"slack_user_id"`
+
+	// IdentitySources orders the sources used to map GitHub logins to Slack
+	// users, first hit wins: endpoint (MappingEndpoint), file (a static
+	// IdentityMappingFile) and email (users.lookupByEmail with the addresses in
+	// IdentityEmailFile). By default every configured source is tried.
+	IdentitySources     []string `envconfig:"SLACK_IDENTITY_SOURCES"`
+	IdentityMappingFile string   `envconfig:"SLACK_IDENTITY_MAPPING_FILE"`
+	IdentityEmailFile   string   `envconfig:"SLACK_IDENTITY_EMAIL_FILE"`
+
+	// GitHubUsers are logins replaced inline with Slack mentions wherever the
+	// message names them; MentionScan does the same for every @login.
+	GitHubUsers []string `envconfig:"GH_USERS"`
+	MentionScan bool     `envconfig:"SLACK_MENTION_SCAN"`
+
+	// MentionGroups are user group handles (e.g. platform-oncall) mentioned at
+	// the start of the message. MentionGroupsExpand applies the membership mode
+	// to their members too.
+	MentionGroups       []string `envconfig:"SLACK_MENTION_GROUPS"`
+	MentionGroupsExpand bool     `envconfig:"SLACK_MENTION_GROUPS_EXPAND"`
+	groupMembers        []string
+
+	// RootUpdate* restyle the thread root after replying to it, e.g. to flip a
+	// "running" root to "succeeded" in the same run as the reply.
+	RootUpdateTitle   string `envconfig:"SLACK_ROOT_UPDATE_TITLE"`
+	RootUpdateMessage string `envconfig:"SLACK_ROOT_UPDATE_MESSAGE"`
+	RootUpdateContext string `envconfig:"SLACK_ROOT_UPDATE_CONTEXT"`
+	RootUpdateColor   string `envconfig:"SLACK_ROOT_UPDATE_COLOR"`
+	RootUpdateStatus  string `envconfig:"SLACK_ROOT_UPDATE_STATUS"`
+	rootUpdate        *config
+
+	// channels resolves channel names to the IDs most API calls require.
+	channels *channelResolver
+
+	// dmChannels maps the @github:<login> and @slack:<id> targets in
+	// SLACK_CHANNEL to the IDs of their DM channels.
+	dmChannels map[string]string
+
+	// EphemeralUser sends the message with chat.postEphemeral, visible only to
+	// this user: a Slack user ID, or a GitHub login resolved via the mapping
+	// endpoint.
+	EphemeralUser   string `envconfig:"SLACK_EPHEMERAL_USER"`
+	ephemeralUserID string
+
+	// Reactions are emoji names added to the message at ReactionTs instead of
+	// sending one.
+	Reactions  []string `envconfig:"SLACK_REACTIONS"`
+	ReactionTs string   `envconfig:"SLACK_REACTION_TS"`
+
+	// command is the subcommand the tool was run with, if any; see cli.go.
+	command string
+
+	// FanOutConcurrency bounds how many channels are posted to at once when
+	// SLACK_CHANNEL is a comma-separated list of channels.
+	FanOutConcurrency int `envconfig:"SLACK_FANOUT_CONCURRENCY" default:"4"`
+
+	// PostAt schedules the message with chat.scheduleMessage, at an RFC 3339
+	// time or a duration from now. DeleteScheduledID cancels a scheduled one.
+	PostAt            string `envconfig:"SLACK_POST_AT"`
+	DeleteScheduledID string `envconfig:"SLACK_DELETE_SCHEDULED_ID"`
+	postAt            time.Time
+
+	// ManifestFile points at a manifest.json written by an earlier run, to
+	// apply ManifestAction (update or delete) to every message recorded in it
+	// instead of to a single SLACK_UPDATE_MESSAGE_TS/SLACK_DELETE_MESSAGE_TS.
+	ManifestFile   string `envconfig:"SLACK_MANIFEST_FILE"`
+	ManifestAction string `envconfig:"SLACK_MANIFEST_ACTION" default:"update"`
+	manifest       *manifest
+
+	// Files is a comma-separated list of glob patterns. Every matching file is
+	// uploaded to the same channel (and thread, for replies) as the message.
+	Files []string `envconfig:"SLACK_FILES"`
+
+	// RetryMaxAttempts and RetryMaxWait bound the retries of every Slack API
+	// call on rate limits, 5xx responses and network errors.
+	RetryMaxAttempts int           `envconfig:"SLACK_RETRY_MAX_ATTEMPTS" default:"5"`
+	RetryMaxWait     time.Duration `envconfig:"SLACK_RETRY_MAX_WAIT" default:"60s"`
+
+	// DryRun prints every Slack API request instead of sending it. Lookups
+	// answer with nothing and writes with placeholder IDs.
+	DryRun bool `envconfig:"SLACK_DRY_RUN"`
+
+	// CIContext set to "auto" appends the repository, branch, commit, run and
+	// actor detected from the CI environment to the context block.
+	CIContext string `envconfig:"SLACK_CI_CONTEXT"`
+
+	// Set by the GitHub Actions runner. When running there, outputs are also
+	// written as step outputs and a step summary.
+	GitHubActions     bool   `envconfig:"GITHUB_ACTIONS"`
+	GitHubOutput      string `envconfig:"GITHUB_OUTPUT"`
+	GitHubStepSummary string `envconfig:"GITHUB_STEP_SUMMARY"`
+
 	// MentionMembershipMode controls what happens to Slack users tagged in the
-	// message: "none" (default, no-op), "invite" (add them to the channel) or
-	// "notify" (DM them a link to the channel).
+	// message: "none" (default, no-op), "invite" (add them to the channel),
+	// "notify" (DM them a link to the channel) or "smart" (invite, falling back
+	// to a DM when the invite isn't allowed).
 	MentionMembershipMode string `envconfig:"SLACK_MENTION_MEMBERSHIP_MODE" default:"none"`
+
+	// MentionNotifyTemplate replaces the DM notify mode sends, as a Go
+	// template over mentionNotice.
+	MentionNotifyTemplate string `envconfig:"SLACK_MENTION_NOTIFY_TEMPLATE"`
+	notifyTemplate        *template.Template
 }
 
 const (
@@ -52,16 +179,21 @@ const (
 	membershipModeNone   membershipMode = "none"
 	membershipModeInvite membershipMode = "invite"
 	membershipModeNotify membershipMode = "notify"
+	membershipModeSmart  membershipMode = "smart"
 )
 
+// channelMembersPageSize is the conversations.members page size; Slack
+// recommends no more than 200.
+const channelMembersPageSize = 200
+
 func parseMembershipMode(s string) (membershipMode, error) {
 	switch membershipMode(s) {
 	case "", membershipModeNone:
 		return membershipModeNone, nil
-	case membershipModeInvite, membershipModeNotify:
+	case membershipModeInvite, membershipModeNotify, membershipModeSmart:
 		return membershipMode(s), nil
 	default:
-		return "", fmt.Errorf("invalid SLACK_MENTION_MEMBERSHIP_MODE %q (valid: none, invite, notify)", s)
+		return "", fmt.Errorf("invalid SLACK_MENTION_MEMBERSHIP_MODE %q (valid: none, invite, notify, smart)", s)
 	}
 }
 
@@ -72,6 +204,7 @@ type slackMembershipClient interface {
 	InviteUsersToConversationContext(ctx context.Context, channelID string, users ...string) (*slack.Channel, error)
 	OpenConversationContext(ctx context.Context, params *slack.OpenConversationParameters) (*slack.Channel, bool, bool, error)
 	PostMessageContext(ctx context.Context, channelID string, options ...slack.MsgOption) (string, string, error)
+	GetUsersInConversationContext(ctx context.Context, params *slack.GetUsersInConversationParameters) ([]string, string, error)
 }
 
 // slackMentionRe matches Slack user mentions: <@U012ABC> or <@W012ABC> (W = grid
@@ -84,36 +217,278 @@ type slackUserNotFoundError struct {
 }
 
 func (e *slackUserNotFoundError) Error() string {
-	return fmt.Sprintf("slack user not found in mapping API for %s", e.GitHubUser)
+	return fmt.Sprintf("slack user not found in mapping for %s", e.GitHubUser)
 }
 
 func (c config) String() string {
-	c.Token = c.Token[:8] + "..."
+	if len(c.Token) > 8 {
+		c.Token = c.Token[:8] + "..."
+	}
+	// Mapping headers usually carry credentials, so only their names are logged.
+	var headers []string
+	for _, h := range c.MappingHeaders {
+		name, _, _ := strings.Cut(h, ":")
+		headers = append(headers, name+": ***")
+	}
+	c.MappingHeaders = headers
 	json, _ := json.MarshalIndent(c, "", "  ")
 	return string(json)
 }
 
 func main() {
-	var cfg config
-	envconfig.MustProcess("", &cfg)
+	command, args, err := parseArgs(os.Args[1:], os.Stderr)
+	if errors.Is(err, flag.ErrHelp) {
+		os.Exit(exitOK)
+	}
+	if err != nil {
+		err = configError(err)
+		slog.Error("Invalid arguments", "exit_code", exitCodeFor(err), "error", err)
+		os.Exit(exitCodeFor(err))
+	}
+
+	if command == commandPreview {
+		err := runPreview(args)
+		if err != nil {
+			slog.Error("Preview failed", "exit_code", exitCodeFor(err), "error", err)
+		}
+		os.Exit(exitCodeFor(err))
+	}
+
+	cfg := config{command: command}
+	res := &result{}
+	err = run(context.Background(), &cfg, res)
+	if err != nil {
+		slog.Error("Run failed", "exit_code", exitCodeFor(err), "error", err)
+		res.setError(err)
+	}
+
+	// The result is written even on failure so downstream steps can branch on
+	// it; a failure to write it only fails an otherwise successful run.
+	if cfg.OutputDir != "" {
+		if werr := writeResult(cfg.OutputDir, res); werr != nil {
+			slog.Error("Failed to write result", "error", werr)
+			if err == nil {
+				err = werr
+			}
+		}
+	}
+
+	os.Exit(exitCodeFor(err))
+}
+
+// run loads the configuration, performs the requested operation and writes the
+// outputs, recording what happened in res as it goes.
+func run(ctx context.Context, cfg *config, res *result) error {
+	if os.Getenv("GITHUB_ACTIONS") == "true" {
+		if err := applyInputAliases(); err != nil {
+			return configError(err)
+		}
+	}
+
+	if err := envconfig.Process("", cfg); err != nil {
+		return configError(err)
+	}
+	// The token is only required to send; previews don't need one.
+	if cfg.Token == "" {
+		return configError(errors.New("required key SLACK_TOKEN missing value"))
+	}
 	slog.Info("Config loaded", "config", cfg.String())
 
 	mode, err := parseMembershipMode(cfg.MentionMembershipMode)
 	if err != nil {
-		slog.Error("Invalid configuration", "error", err)
-		os.Exit(1)
+		return configError(err)
+	}
+
+	if cfg.notifyTemplate, err = parseNotifyTemplate(cfg.MentionNotifyTemplate); err != nil {
+		return configError(err)
+	}
+
+	if err := loadBlocks(cfg); err != nil {
+		return configError(err)
+	}
+
+	if err := renderTemplates(cfg, time.Now); err != nil {
+		return configError(err)
+	}
+
+	if err := prepareRootUpdate(cfg, time.Now); err != nil {
+		return configError(err)
+	}
+
+	if err := applyCIContext(cfg, os.Getenv); err != nil {
+		return configError(err)
+	}
+
+	if err := applyStatus(cfg); err != nil {
+		return configError(err)
+	}
+
+	if cfg.UpdateTs != "" && cfg.DeleteTs != "" {
+		return configError(errors.New("cannot update and delete a message at the same time"))
+	}
+
+	if err := loadManifest(cfg); err != nil {
+		return configError(err)
+	}
+
+	if err := prepareSchedule(cfg, time.Now()); err != nil {
+		return configError(err)
+	}
+
+	if _, err := identitySources(*cfg); err != nil {
+		return configError(err)
+	}
+
+	if err := validateEphemeral(*cfg); err != nil {
+		return configError(err)
+	}
+
+	if err := validateDMTargets(*cfg); err != nil {
+		return configError(err)
+	}
+
+	if err := validateReactions(*cfg); err != nil {
+		return configError(err)
+	}
+
+	if err := validateCommand(*cfg); err != nil {
+		return configError(err)
+	}
+
+	if cfg.Channel == "" && cfg.manifest == nil {
+		return configError(errors.New("SLACK_CHANNEL is required"))
+	}
+
+	if len(splitChannels(cfg.Channel)) > 1 && (cfg.ThreadTs != "" || cfg.UpdateTs != "" || cfg.DeleteTs != "" || cfg.DeleteScheduledID != "" || cfg.ReactionTs != "") {
+		return configError(errors.New("SLACK_CHANNEL lists several channels, which only works for new messages"))
+	}
+
+	if cfg.FanOutConcurrency < 1 {
+		return configError(errors.New("SLACK_FANOUT_CONCURRENCY must be at least 1"))
 	}
 
-	slackClient := slack.New(cfg.Token)
+	var api slackAPI = slack.New(cfg.Token)
+	if cfg.DryRun {
+		slog.Info("Dry run: printing Slack API requests instead of sending them")
+		api = newDryRunClient(cfg.Token, os.Stdout)
+	}
+	slackClient := &retryingClient{
+		api:    api,
+		policy: newRetryPolicy(cfg.RetryMaxAttempts, cfg.RetryMaxWait),
+	}
 	httpClient := &http.Client{
 		Timeout: slackMentionTimeout,
 	}
+	// Channel names can't be looked up without Slack, so a dry run prints
+	// them as given.
+	if !cfg.DryRun {
+		cfg.channels = newChannelResolver(slackClient, cfg.OutputDir)
+	}
+	identities, err := newIdentityResolver(*cfg, httpClient, slackClient)
+	if err != nil {
+		return configError(err)
+	}
 
-	cfg.Message = prependSlackMention(context.Background(), cfg, httpClient)
+	cfg.Message = prependSlackMention(ctx, *cfg, identities)
+	cfg.Message = mentionGitHubUsers(ctx, *cfg, identities)
+	cfg.Message, cfg.groupMembers = mentionUserGroups(ctx, *cfg, mode, slackClient)
 
-	if cfg.UpdateTs != "" && cfg.DeleteTs != "" {
-		slog.Error("Cannot update and delete a message at the same time")
-		os.Exit(1)
+	cfg.dmChannels, err = openDMChannels(ctx, *cfg, identities, slackClient)
+	if err != nil {
+		return err
+	}
+
+	if cfg.EphemeralUser != "" {
+		cfg.ephemeralUserID, err = resolveEphemeralUser(ctx, *cfg, identities)
+		if err != nil {
+			return err
+		}
+	}
+
+	return execute(ctx, *cfg, mode, slackClient, res)
+}
+
+// execute performs the operation described by the validated cfg and writes the
+// outputs, fanning out when SLACK_CHANNEL lists several channels.
+func execute(ctx context.Context, cfg config, mode membershipMode, slackClient slackAPI, res *result) error {
+	// A dry run only has placeholder IDs, which later steps must never pick up
+	// as the outputs of a real run: only result.json is written, marked as a
+	// dry run.
+	if cfg.DryRun {
+		res.DryRun = true
+		cfg.OutputDir, cfg.GitHubActions = "", false
+	}
+
+	var err error
+	channels := splitChannels(cfg.Channel)
+	switch {
+	case cfg.manifest != nil:
+		err = fanOut(ctx, cfg, cfg.manifest.targets(cfg.ManifestAction), mode, slackClient, res)
+	case len(channels) > 1:
+		err = fanOut(ctx, cfg, channelTargets(channels), mode, slackClient, res)
+	default:
+		if len(channels) == 1 {
+			cfg.Channel = channels[0]
+		}
+		err = sendToChannel(ctx, cfg, mode, slackClient, res)
+	}
+
+	// Record every message sent so a later run can update or delete them all.
+	if cfg.OutputDir != "" {
+		if m := buildManifest(cfg, res); len(m.Messages) > 0 || res.Operation == "delete" {
+			err = errors.Join(err, writeManifest(cfg.OutputDir, m))
+		}
+	}
+	return err
+}
+
+// sendToChannel performs the operation in the single channel cfg.Channel and
+// writes the outputs.
+func sendToChannel(ctx context.Context, cfg config, mode membershipMode, slackClient slackAPI, res *result) error {
+	res.Operation = operation(cfg)
+	dmChannelID, isDM := cfg.dmChannels[cfg.Channel]
+	switch {
+	case isDM:
+		cfg.Channel = dmChannelID
+	case cfg.channels != nil:
+		// Sending works with a name, so a failed lookup (a token without
+		// channels:read, a channel the bot can't list) only costs the ID.
+		channelID, err := cfg.channels.resolve(ctx, cfg.Channel)
+		switch {
+		case err == nil:
+			cfg.Channel = channelID
+		case channelIDRequired(cfg):
+			return err
+		default:
+			slog.Warn("Failed to resolve channel, sending to it by name", "channel", cfg.Channel, "error", err)
+		}
+	}
+	switch {
+	case cfg.DeleteScheduledID != "":
+		if err := deleteScheduledMessage(ctx, cfg, slackClient, res); err != nil {
+			return err
+		}
+		return writeRunOutputs(cfg, res)
+	case !cfg.postAt.IsZero():
+		if err := scheduleMessage(ctx, cfg, slackClient, res); err != nil {
+			return err
+		}
+		return writeRunOutputs(cfg, res)
+	case cfg.ephemeralUserID != "":
+		if err := postEphemeral(ctx, cfg, slackClient, res); err != nil {
+			return err
+		}
+		return writeRunOutputs(cfg, res)
+	case cfg.ReactionTs != "":
+		if err := addReactions(ctx, cfg, slackClient, res); err != nil {
+			return err
+		}
+		return writeRunOutputs(cfg, res)
+	case cfg.command == commandUpload:
+		if err := uploadOnly(ctx, cfg, slackClient, res); err != nil {
+			return err
+		}
+		return writeRunOutputs(cfg, res)
 	}
 
 	// Send the message
@@ -128,16 +503,9 @@ func main() {
 			options = append(options, slack.MsgOptionBroadcast())
 		}
 	}
-	channelID, messageTs, _, err := slackClient.SendMessage(cfg.Channel, options...)
+	channelID, messageTs, _, err := slackClient.SendMessageContext(ctx, cfg.Channel, options...)
 	if err != nil {
-		panic(err)
-	}
-
-	// Best-effort: invite or notify any users tagged in the message who may not
-	// be in the channel. Only for new messages/replies — for update the original
-	// send already handled it, and a delete has nothing to be mentioned in.
-	if cfg.UpdateTs == "" && cfg.DeleteTs == "" {
-		ensureMentionMembership(context.Background(), slackClient, mode, channelID, cfg.Message)
+		return fmt.Errorf("%s message: %w", res.Operation, err)
 	}
 
 	// threadTs is the timestamp of the root message of a thread.
@@ -148,28 +516,176 @@ func main() {
 	if threadTs == "" {
 		threadTs = messageTs
 	}
+	res.ChannelID, res.MessageTs, res.ThreadTs = channelID, messageTs, threadTs
+
+	// Links to the message and its thread root, for PR comments and tickets.
+	// A root message is its own thread root, so it needs only one lookup.
+	if cfg.DeleteTs == "" {
+		res.Permalink = fetchPermalink(ctx, slackClient, channelID, messageTs)
+		res.ThreadPermalink = res.Permalink
+		if threadTs != messageTs {
+			res.ThreadPermalink = fetchPermalink(ctx, slackClient, channelID, threadTs)
+		}
+	}
+
+	// Best-effort: invite or notify any users tagged in the message who may not
+	// be in the channel. Only for new messages/replies — for update the original
+	// send already handled it, and a delete has nothing to be mentioned in. Nobody
+	// can be invited into a DM.
+	if cfg.UpdateTs == "" && cfg.DeleteTs == "" && !isDM {
+		ensureMentionMembership(ctx, slackClient, mode, newMentionNotice(cfg, channelID, res.Permalink), cfg.groupMembers...)
+	}
+
+	// Upload files next to the message: into the thread when replying, into the
+	// channel otherwise. A deleted message has nothing to attach to. A failed
+	// upload still writes the outputs for the message that was sent.
+	var errs []error
+	if len(cfg.Files) > 0 && cfg.DeleteTs == "" {
+		var err error
+		res.FileIDs, err = uploadFiles(ctx, slackClient, channelID, cfg.ThreadTs, cfg.Files)
+		errs = append(errs, err)
+	}
+
+	// Restyle the thread root once the reply is in, so the two never disagree
+	// for long. Like an upload, a failure here still writes the reply outputs.
+	if cfg.rootUpdate != nil {
+		errs = append(errs, updateThreadRoot(ctx, slackClient, *cfg.rootUpdate, channelID, threadTs, res))
+	}
+
+	if err := writeRunOutputs(cfg, res); err != nil {
+		return err
+	}
+	return errors.Join(errs...)
+}
 
-	// Write the channelID, messageTs and threadTs to a file to be reused in another container (For example, steps in Argo Workflows)
-	// ThreadTs: timestamp of the root message of a thread
-	// MessageTs: timestamp of the message (root or reply)
-	// ChannelID: ID of the channel where the message was sent. This is required to update messages. The API requires the ID, not the name.
+// writeRunOutputs writes res to the output directory and, on GitHub Actions,
+// as step outputs.
+func writeRunOutputs(cfg config, res *result) error {
 	if cfg.OutputDir != "" {
-		slog.Info("channel-id written", "channel_id", channelID)
-		if err := os.WriteFile(filepath.Join(cfg.OutputDir, "channel-id"), []byte(channelID), 0644); err != nil {
-			panic(err)
+		if err := writeOutputs(cfg.OutputDir, res); err != nil {
+			return err
 		}
-		slog.Info("message-ts written", "message_ts", messageTs)
-		if err := os.WriteFile(filepath.Join(cfg.OutputDir, "message-ts"), []byte(messageTs), 0644); err != nil {
-			panic(err)
-		}
-		slog.Info("thread-ts written", "thread_ts", threadTs)
-		if err := os.WriteFile(filepath.Join(cfg.OutputDir, "thread-ts"), []byte(threadTs), 0644); err != nil {
-			panic(err)
+	}
+	if cfg.GitHubActions {
+		return writeGitHubOutputs(cfg, res)
+	}
+	return nil
+}
+
+// operation names what the run does, as recorded in result.json: post, reply,
+// update, delete, schedule, delete_scheduled, ephemeral, react or upload.
+func operation(cfg config) string {
+	switch {
+	case cfg.command == commandUpload:
+		return "upload"
+	case cfg.ReactionTs != "":
+		return "react"
+	case cfg.EphemeralUser != "":
+		return "ephemeral"
+	case cfg.DeleteScheduledID != "":
+		return "delete_scheduled"
+	case cfg.PostAt != "":
+		return "schedule"
+	case cfg.UpdateTs != "":
+		return "update"
+	case cfg.DeleteTs != "":
+		return "delete"
+	case cfg.ThreadTs != "":
+		return "reply"
+	default:
+		return "post"
+	}
+}
+
+// fetchPermalink returns a link to the message, or "" if Slack can't provide
+// one. A missing permalink never fails the run.
+func fetchPermalink(ctx context.Context, client slackAPI, channelID, ts string) string {
+	permalink, err := client.GetPermalinkContext(ctx, &slack.PermalinkParameters{Channel: channelID, Ts: ts})
+	if err != nil {
+		slog.Warn("Failed to get permalink", "channel_id", channelID, "ts", ts, "error", err)
+		return ""
+	}
+	return permalink
+}
+
+// writeOutputs writes the channelID, messageTs and threadTs to files to be reused in another container (For example, steps in Argo Workflows)
+// ThreadTs: timestamp of the root message of a thread
+// MessageTs: timestamp of the message (root or reply)
+// ChannelID: ID of the channel where the message was sent. This is required to update messages. The API requires the ID, not the name.
+// Permalink: link to the message
+// ThreadPermalink: link to the root message of the thread
+// RootMessageTs: timestamp of the thread root, only when SLACK_ROOT_UPDATE_* restyled it
+// FileIDs: IDs of the files uploaded via SLACK_FILES, one per line
+// ScheduledMessageID: ID of the message scheduled via SLACK_POST_AT, needed to cancel it
+// Every file is written on every run, empty when it doesn't apply, so a reused
+// output directory never keeps a value from an earlier run.
+func writeOutputs(dir string, res *result) error {
+	var rootMessageTs string
+	if res.RootUpdate != nil {
+		rootMessageTs = res.RootUpdate.MessageTs
+	}
+	outputs := []struct{ name, value string }{
+		{"channel-id", res.ChannelID},
+		{"message-ts", res.MessageTs},
+		{"thread-ts", res.ThreadTs},
+		{"scheduled-message-id", res.ScheduledMessageID},
+		{"permalink", res.Permalink},
+		{"thread-permalink", res.ThreadPermalink},
+		{"root-message-ts", rootMessageTs},
+		{"file-ids", strings.Join(res.FileIDs, "\n")},
+	}
+
+	for _, o := range outputs {
+		if err := os.WriteFile(filepath.Join(dir, o.name), []byte(o.value), 0644); err != nil {
+			return outputError(fmt.Errorf("write %s: %w", o.name, err))
 		}
+		slog.Info(o.name+" written", strings.ReplaceAll(o.name, "-", "_"), o.value)
 	}
+	return nil
 }
 
 func content(cfg config) slack.MsgOption {
+	body := newMessageBody(cfg)
+	if body.attachment != nil {
+		return slack.MsgOptionAttachments(*body.attachment)
+	}
+	return slack.MsgOptionCompose(
+		slack.MsgOptionText(body.fallback, false),
+		slack.MsgOptionBlocks(body.blocks...),
+	)
+}
+
+// messageBody is what content sends: top-level blocks with a fallback text, or
+// a colored attachment.
+type messageBody struct {
+	fallback   string
+	blocks     []slack.Block
+	attachment *slack.Attachment
+}
+
+func newMessageBody(cfg config) messageBody {
+	fallback := firstNonEmpty(cfg.Message, cfg.Title, cfg.fallback)
+
+	// Raw Block Kit payloads are sent as-is, either as top-level blocks or
+	// inside the colored attachment.
+	if len(cfg.blocks) > 0 && !cfg.BlocksInAttachment {
+		return messageBody{fallback: fallback, blocks: cfg.blocks}
+	}
+
+	blocks := cfg.blocks
+	if len(blocks) == 0 {
+		blocks = defaultBlocks(cfg)
+	}
+
+	return messageBody{fallback: fallback, attachment: &slack.Attachment{
+		Fallback: fallback,
+		Blocks:   slack.Blocks{BlockSet: blocks},
+		Color:    cfg.Color,
+	}}
+}
+
+// defaultBlocks builds the title, message and context blocks from the config.
+func defaultBlocks(cfg config) []slack.Block {
 	var blocks []slack.Block
 	if cfg.Title != "" {
 		blocks = append(blocks, slack.NewSectionBlock(
@@ -188,24 +704,13 @@ func content(cfg config) slack.MsgOption {
 		))
 	}
 
-	fallback := cfg.Message
-	if fallback == "" {
-		fallback = cfg.Title
-	}
-
-	attachment := slack.Attachment{
-		Fallback: fallback,
-		Blocks:   slack.Blocks{BlockSet: blocks},
-		Color:    cfg.Color,
-	}
-
-	return slack.MsgOptionAttachments(attachment)
+	return blocks
 }
 
-func prependSlackMention(ctx context.Context, cfg config, httpClient *http.Client) string {
+func prependSlackMention(ctx context.Context, cfg config, identities identityResolver) string {
 	message := cfg.Message
-	if cfg.MappingEndpoint == "" {
-		slog.Info("GITHUB_SLACK_MAPPING_ENDPOINT not set, skipping mention")
+	if identities == nil {
+		slog.Info("No GitHub-to-Slack mapping configured, skipping mention")
 		return message
 	}
 
@@ -225,11 +730,11 @@ func prependSlackMention(ctx context.Context, cfg config, httpClient *http.Clien
 		return message
 	}
 
-	slackID, err := fetchSlackUserID(ctx, httpClient, ghUser, cfg.MappingEndpoint)
+	slackID, err := identities.SlackUserID(ctx, ghUser)
 	if err != nil {
 		var notFoundErr *slackUserNotFoundError
 		if errors.As(err, &notFoundErr) {
-			slog.Warn("GitHub user not found in mapping API, skipping mention", "github_user", notFoundErr.GitHubUser)
+			slog.Warn("GitHub user not found in mapping, skipping mention", "github_user", notFoundErr.GitHubUser)
 		} else {
 			slog.Error("Failed to fetch Slack user, skipping mention", "github_user", ghUser, "error", err)
 		}
@@ -257,30 +762,75 @@ func extractMentionedUserIDs(message string) []string {
 }
 
 // ensureMentionMembership invites or notifies (per mode) the users tagged in the
-// message. It is best-effort: every failure is logged, none is fatal.
-func ensureMentionMembership(ctx context.Context, client slackMembershipClient, mode membershipMode, channelID, message string) {
+// message, plus extraIDs (members of mentioned user groups). It is best-effort:
+// every failure is logged, none is fatal.
+func ensureMentionMembership(ctx context.Context, client slackMembershipClient, mode membershipMode, notice mentionNotice, extraIDs ...string) {
 	if mode == membershipModeNone {
 		return
 	}
 
-	ids := extractMentionedUserIDs(message)
+	channelID := notice.ChannelID
+	ids := extractMentionedUserIDs(notice.Message)
+	for _, id := range extraIDs {
+		if !slices.Contains(ids, id) {
+			ids = append(ids, id)
+		}
+	}
 	if len(ids) == 0 {
 		slog.Info("No Slack mentions found, skipping membership step")
 		return
 	}
 
+	// Skip users who are already in the channel. If the members can't be
+	// listed, act on everyone as before.
+	members, err := channelMembers(ctx, client, channelID)
+	if err != nil {
+		slog.Warn("Failed to list channel members, acting on every mentioned user", "channel_id", channelID, "error", err)
+	}
+	ids = slices.DeleteFunc(ids, func(id string) bool {
+		_, ok := members[id]
+		return ok
+	})
+	if len(ids) == 0 {
+		slog.Info("Every mentioned user is already in the channel", "channel_id", channelID)
+		return
+	}
+
 	switch mode {
 	case membershipModeInvite:
 		inviteMentionedUsers(ctx, client, channelID, ids)
 	case membershipModeNotify:
-		notifyMentionedUsers(ctx, client, channelID, ids)
+		notifyMentionedUsers(ctx, client, notice, ids)
+	case membershipModeSmart:
+		notifyMentionedUsers(ctx, client, notice, inviteMentionedUsers(ctx, client, channelID, ids))
+	}
+}
+
+// channelMembers pages through conversations.members.
+func channelMembers(ctx context.Context, client slackMembershipClient, channelID string) (map[string]struct{}, error) {
+	members := make(map[string]struct{})
+	params := &slack.GetUsersInConversationParameters{ChannelID: channelID, Limit: channelMembersPageSize}
+	for {
+		ids, cursor, err := client.GetUsersInConversationContext(ctx, params)
+		if err != nil {
+			return nil, err
+		}
+		for _, id := range ids {
+			members[id] = struct{}{}
+		}
+		if cursor == "" {
+			return members, nil
+		}
+		params.Cursor = cursor
 	}
 }
 
 // inviteMentionedUsers invites each user individually. conversations.invite is
 // all-or-nothing per call, so a single already-member id would otherwise block
-// inviting everyone else.
-func inviteMentionedUsers(ctx context.Context, client slackMembershipClient, channelID string, ids []string) {
+// inviting everyone else. It returns the users the invite wasn't allowed for,
+// whom smart mode DMs instead.
+func inviteMentionedUsers(ctx context.Context, client slackMembershipClient, channelID string, ids []string) []string {
+	var refused []string
 	for _, id := range ids {
 		_, err := client.InviteUsersToConversationContext(ctx, channelID, id)
 		if err == nil {
@@ -291,14 +841,28 @@ func inviteMentionedUsers(ctx context.Context, client slackMembershipClient, cha
 		switch err.Error() {
 		case "already_in_channel", "cant_invite_self", "user_is_bot":
 			slog.Info("Invite no-op", "reason", err.Error(), "user", id)
+		case "not_in_channel", "restricted_action":
+			slog.Warn("Invite not allowed", "channel_id", channelID, "user", id, "reason", err.Error())
+			refused = append(refused, id)
 		default:
 			slog.Warn("Failed to invite user to channel", "channel_id", channelID, "user", id, "error", err)
 		}
 	}
+	return refused
 }
 
-// notifyMentionedUsers DMs each mentioned user a link to the channel.
-func notifyMentionedUsers(ctx context.Context, client slackMembershipClient, channelID string, ids []string) {
+// notifyMentionedUsers DMs each mentioned user the notice: a link to the
+// message with its title and a snippet.
+func notifyMentionedUsers(ctx context.Context, client slackMembershipClient, notice mentionNotice, ids []string) {
+	if len(ids) == 0 {
+		return
+	}
+	options, err := notice.options()
+	if err != nil {
+		slog.Warn("Failed to render mention notice", "error", err)
+		return
+	}
+
 	for _, id := range ids {
 		ch, _, _, err := client.OpenConversationContext(ctx, &slack.OpenConversationParameters{Users: []string{id}})
 		if err != nil {
@@ -306,8 +870,7 @@ func notifyMentionedUsers(ctx context.Context, client slackMembershipClient, cha
 			continue
 		}
 
-		text := fmt.Sprintf("You were mentioned in <#%s>.", channelID)
-		if _, _, err := client.PostMessageContext(ctx, ch.ID, slack.MsgOptionText(text, false)); err != nil {
+		if _, _, err := client.PostMessageContext(ctx, ch.ID, options...); err != nil {
 			slog.Warn("Failed to send DM", "user", id, "error", err)
 		}
 	}
@@ -319,40 +882,8 @@ func containsGitHubUsername(message, username string) bool {
 	return re.MatchString(message)
 }
 
-type slackMappingResponse struct {
-	SlackUserID string `json:"slack_user_id"`
-}
-
+// fetchSlackUserID queries the mapping endpoint with the default request
+// shape: GET <endpoint><login>, answering {"slack_user_id": "U..."}.
 func fetchSlackUserID(ctx context.Context, httpClient *http.Client, ghUser string, endpoint string) (string, error) {
-	url := endpoint + ghUser
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
-	if err != nil {
-		return "", fmt.Errorf("create mapping request: %w", err)
-	}
-
-	resp, err := httpClient.Do(req)
-	if err != nil {
-		return "", fmt.Errorf("execute mapping request: %w", err)
-	}
-	defer resp.Body.Close()
-
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return "", fmt.Errorf("read mapping response: %w", err)
-	}
-
-	if resp.StatusCode == http.StatusNotFound {
-		return "", &slackUserNotFoundError{GitHubUser: ghUser}
-	}
-
-	if resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("mapping API returned status %d: %s", resp.StatusCode, body)
-	}
-
-	var payload slackMappingResponse
-	if err := json.Unmarshal(body, &payload); err != nil {
-		return "", fmt.Errorf("unmarshal mapping response: %w", err)
-	}
-
-	return payload.SlackUserID, nil
+	return (&endpointResolver{client: httpClient, endpoint: endpoint}).SlackUserID(ctx, ghUser)
 }
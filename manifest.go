@@ -0,0 +1,139 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+)
+
+const manifestVersion = 1
+
+// manifest records every message a run sent, so a later run can update or
+// delete them all with SLACK_MANIFEST_FILE. It is written as manifest.json in
+// the output directory.
+type manifest struct {
+	Version  int               `json:"version"`
+	Messages []manifestMessage `json:"messages"`
+}
+
+type manifestMessage struct {
+	// Channel is the channel as originally given; it keys the per-message
+	// results and output subdirectories.
+	Channel   string `json:"channel,omitempty"`
+	ChannelID string `json:"channel_id"`
+	Ts        string `json:"ts"`
+	ThreadTs  string `json:"thread_ts,omitempty"`
+}
+
+// loadManifest reads SLACK_MANIFEST_FILE, if set, into cfg.
+func loadManifest(cfg *config) error {
+	if cfg.ManifestFile == "" {
+		return nil
+	}
+	if cfg.ThreadTs != "" || cfg.UpdateTs != "" || cfg.DeleteTs != "" {
+		return errors.New("SLACK_MANIFEST_FILE can't be combined with SLACK_THREAD_TS, SLACK_UPDATE_MESSAGE_TS or SLACK_DELETE_MESSAGE_TS")
+	}
+	if cfg.ManifestAction != "update" && cfg.ManifestAction != "delete" {
+		return fmt.Errorf("invalid SLACK_MANIFEST_ACTION %q (valid: update, delete)", cfg.ManifestAction)
+	}
+
+	data, err := os.ReadFile(cfg.ManifestFile)
+	if err != nil {
+		return fmt.Errorf("read SLACK_MANIFEST_FILE: %w", err)
+	}
+	var m manifest
+	if err := json.Unmarshal(data, &m); err != nil {
+		return fmt.Errorf("decode SLACK_MANIFEST_FILE: %w", err)
+	}
+	if m.Version != manifestVersion {
+		return fmt.Errorf("unsupported manifest version %d", m.Version)
+	}
+	if len(m.Messages) == 0 {
+		return errors.New("manifest has no messages")
+	}
+	for i, msg := range m.Messages {
+		if msg.ChannelID == "" || msg.Ts == "" {
+			return fmt.Errorf("manifest message %d is missing channel_id or ts", i)
+		}
+	}
+
+	cfg.manifest = &m
+	return nil
+}
+
+// targets returns one fan-out target per message applying action to it. Keys
+// are the original channels, made unique when a channel holds several
+// messages.
+func (m manifest) targets(action string) []fanOutTarget {
+	seen := make(map[string]struct{}, len(m.Messages))
+	targets := make([]fanOutTarget, 0, len(m.Messages))
+	for _, msg := range m.Messages {
+		key := firstNonEmpty(msg.Channel, msg.ChannelID)
+		if _, ok := seen[key]; ok {
+			key += "@" + msg.Ts
+		}
+		seen[key] = struct{}{}
+
+		t := fanOutTarget{key: key, channel: msg.ChannelID}
+		if msg.ThreadTs != msg.Ts {
+			t.threadTs = msg.ThreadTs
+		}
+		if action == "delete" {
+			t.deleteTs = msg.Ts
+		} else {
+			t.updateTs = msg.Ts
+		}
+		targets = append(targets, t)
+	}
+	return targets
+}
+
+// buildManifest lists the messages the run sent. A delete leaves only the
+// messages of a manifest whose delete failed, so an earlier manifest.json in
+// the output directory stops listing deleted messages. Ephemeral messages
+// can't be targeted again and reactions send no message. An update driven by
+// a manifest carries the input manifest forward whole, so messages whose
+// update failed can still be targeted later.
+func buildManifest(cfg config, res *result) manifest {
+	m := manifest{Version: manifestVersion}
+	switch {
+	case res.Operation == "delete":
+		m.Messages = []manifestMessage{}
+		if cfg.manifest != nil {
+			for i, t := range cfg.manifest.targets(cfg.ManifestAction) {
+				if r := res.Channels[t.key]; r == nil || r.Error != nil {
+					m.Messages = append(m.Messages, cfg.manifest.Messages[i])
+				}
+			}
+		}
+	case res.Operation == "ephemeral", res.Operation == "react":
+	case cfg.manifest != nil:
+		m.Messages = cfg.manifest.Messages
+	case len(res.Channels) > 0:
+		for _, key := range slices.Sorted(maps.Keys(res.Channels)) {
+			r := res.Channels[key]
+			if r.ChannelID == "" || r.MessageTs == "" {
+				continue
+			}
+			m.Messages = append(m.Messages, manifestMessage{Channel: key, ChannelID: r.ChannelID, Ts: r.MessageTs, ThreadTs: r.ThreadTs})
+		}
+	case res.ChannelID != "" && res.MessageTs != "":
+		m.Messages = []manifestMessage{{Channel: cfg.Channel, ChannelID: res.ChannelID, Ts: res.MessageTs, ThreadTs: res.ThreadTs}}
+	}
+	return m
+}
+
+func writeManifest(dir string, m manifest) error {
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return outputError(fmt.Errorf("marshal manifest: %w", err))
+	}
+	if err := os.WriteFile(filepath.Join(dir, "manifest.json"), data, 0644); err != nil {
+		return outputError(fmt.Errorf("write manifest.json: %w", err))
+	}
+	return nil
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/slack-go/slack"
+	"github.com/stretchr/testify/require"
+)
+
+func writeManifestFile(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "manifest.json")
+	require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+	return path
+}
+
+func TestLoadManifest(t *testing.T) {
+	t.Parallel()
+
+	valid := writeManifestFile(t, `{"version":1,"messages":[{"channel":"#deploys","channel_id":"C1","ts":"1.1","thread_ts":"1.1"}]}`)
+
+	tests := []struct {
+		name      string
+		cfg       config
+		expectErr bool
+	}{
+		{name: "unset", cfg: config{}},
+		{name: "valid", cfg: config{ManifestFile: valid, ManifestAction: "delete"}},
+		{name: "bad action", cfg: config{ManifestFile: valid, ManifestAction: "react"}, expectErr: true},
+		{name: "combined with update ts", cfg: config{ManifestFile: valid, ManifestAction: "update", UpdateTs: "1.1"}, expectErr: true},
+		{name: "missing file", cfg: config{ManifestFile: filepath.Join(t.TempDir(), "nope.json"), ManifestAction: "update"}, expectErr: true},
+		{name: "wrong version", cfg: config{ManifestFile: writeManifestFile(t, `{"version":2,"messages":[{"channel_id":"C1","ts":"1.1"}]}`), ManifestAction: "update"}, expectErr: true},
+		{name: "empty", cfg: config{ManifestFile: writeManifestFile(t, `{"version":1,"messages":[]}`), ManifestAction: "update"}, expectErr: true},
+		{name: "missing ts", cfg: config{ManifestFile: writeManifestFile(t, `{"version":1,"messages":[{"channel_id":"C1"}]}`), ManifestAction: "update"}, expectErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			cfg := tt.cfg
+			err := loadManifest(&cfg)
+			if tt.expectErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, cfg.ManifestFile != "", cfg.manifest != nil)
+		})
+	}
+}
+
+func TestManifestTargets(t *testing.T) {
+	t.Parallel()
+
+	m := manifest{Version: 1, Messages: []manifestMessage{
+		{Channel: "#deploys", ChannelID: "C1", Ts: "1.1", ThreadTs: "1.1"},
+		{Channel: "#deploys", ChannelID: "C1", Ts: "2.2", ThreadTs: "1.1"},
+		{ChannelID: "C2", Ts: "3.3"},
+	}}
+
+	require.Equal(t, []fanOutTarget{
+		{key: "#deploys", channel: "C1", updateTs: "1.1"},
+		{key: "#deploys@2.2", channel: "C1", threadTs: "1.1", updateTs: "2.2"},
+		{key: "C2", channel: "C2", updateTs: "3.3"},
+	}, m.targets("update"))
+	require.Equal(t, "3.3", m.targets("delete")[2].deleteTs)
+}
+
+func TestExecuteManifest(t *testing.T) {
+	t.Parallel()
+
+	t.Run("fan-out post writes manifest", func(t *testing.T) {
+		t.Parallel()
+		dir := t.TempDir()
+		cfg := config{Channel: "#deploys,#team", OutputDir: dir, FanOutConcurrency: 2}
+		require.NoError(t, execute(context.Background(), cfg, membershipModeNone, &fanOutSlackAPI{}, &result{}))
+
+		var m manifest
+		require.NoError(t, json.Unmarshal([]byte(readOutput(t, dir, "manifest.json")), &m))
+		require.Equal(t, manifest{Version: 1, Messages: []manifestMessage{
+			{Channel: "#deploys", ChannelID: "C-deploys", Ts: "111.222", ThreadTs: "111.222"},
+			{Channel: "#team", ChannelID: "C-team", Ts: "111.222", ThreadTs: "111.222"},
+		}}, m)
+	})
+
+	t.Run("delete every message and report each", func(t *testing.T) {
+		t.Parallel()
+		dir := t.TempDir()
+		cfg := config{
+			OutputDir:      dir,
+			ManifestAction: "delete",
+			ManifestFile: writeManifestFile(t, `{"version":1,"messages":[`+
+				`{"channel":"#deploys","channel_id":"C1","ts":"1.1"},`+
+				`{"channel":"#team","channel_id":"C2","ts":"2.2"}]}`),
+			FanOutConcurrency: 1,
+		}
+		require.NoError(t, loadManifest(&cfg))
+
+		api := &fanOutSlackAPI{failing: map[string]error{"C2": slack.SlackErrorResponse{Err: "message_not_found"}}}
+		res := &result{}
+		err := execute(context.Background(), cfg, membershipModeNone, api, res)
+		require.Error(t, err)
+		require.Equal(t, "delete", res.Operation)
+		require.Nil(t, res.Channels["#deploys"].Error)
+		require.Equal(t, "delete", res.Channels["#deploys"].Operation)
+		require.NotNil(t, res.Channels["#team"].Error)
+
+		var m manifest
+		require.NoError(t, json.Unmarshal([]byte(readOutput(t, dir, "manifest.json")), &m))
+		require.Equal(t, manifest{Version: 1, Messages: []manifestMessage{
+			{Channel: "#team", ChannelID: "C2", Ts: "2.2"},
+		}}, m, "only the message whose delete failed is left")
+	})
+
+	t.Run("delete empties an earlier manifest", func(t *testing.T) {
+		t.Parallel()
+		dir := t.TempDir()
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "manifest.json"),
+			[]byte(`{"version":1,"messages":[{"channel_id":"C123","ts":"1.1"}]}`), 0644))
+
+		cfg := config{Channel: "C123", DeleteTs: "1.1", OutputDir: dir}
+		require.NoError(t, execute(context.Background(), cfg, membershipModeNone, &flakySlackAPI{}, &result{}))
+		require.JSONEq(t, `{"version":1,"messages":[]}`, readOutput(t, dir, "manifest.json"))
+	})
+}
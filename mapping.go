@@ -0,0 +1,176 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+)
+
+const defaultMappingSelector = "slack_user_id"
+
+// endpointResolver queries GITHUB_SLACK_MAPPING_ENDPOINT. The zero values of
+// headers and selector give the default request shape.
+type endpointResolver struct {
+	client   *http.Client
+	endpoint string
+	headers  http.Header
+	selector jsonSelector
+}
+
+// newEndpointResolver builds the resolver for the configured request shape.
+func newEndpointResolver(cfg config, httpClient *http.Client) (*endpointResolver, error) {
+	r := &endpointResolver{client: httpClient, endpoint: cfg.MappingEndpoint, headers: make(http.Header)}
+
+	for _, h := range cfg.MappingHeaders {
+		name, value, ok := strings.Cut(h, ":")
+		name = strings.TrimSpace(name)
+		if !ok || name == "" {
+			return nil, fmt.Errorf("invalid GITHUB_SLACK_MAPPING_HEADERS entry %q: want \"Name: value\"", h)
+		}
+		r.headers.Add(name, strings.TrimSpace(value))
+	}
+
+	if cfg.MappingTokenFile != "" {
+		token, err := os.ReadFile(cfg.MappingTokenFile)
+		if err != nil {
+			return nil, fmt.Errorf("read GITHUB_SLACK_MAPPING_TOKEN_FILE: %w", err)
+		}
+		r.headers.Set("Authorization", "Bearer "+strings.TrimSpace(string(token)))
+	}
+
+	selector, err := parseJSONSelector(firstNonEmpty(cfg.MappingSelector, defaultMappingSelector))
+	if err != nil {
+		return nil, fmt.Errorf("invalid GITHUB_SLACK_MAPPING_SELECTOR: %w", err)
+	}
+	r.selector = selector
+	return r, nil
+}
+
+// url fills the {login} and {login_lower} placeholders of the endpoint, or
+// appends the login when it has none.
+func (r *endpointResolver) url(ghUser string) string {
+	if !strings.Contains(r.endpoint, "{login") {
+		return r.endpoint + ghUser
+	}
+	return strings.NewReplacer(
+		"{login}", url.PathEscape(ghUser),
+		"{login_lower}", url.PathEscape(strings.ToLower(ghUser)),
+	).Replace(r.endpoint)
+}
+
+func (r *endpointResolver) SlackUserID(ctx context.Context, ghUser string) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, r.url(ghUser), nil)
+	if err != nil {
+		return "", fmt.Errorf("create mapping request: %w", err)
+	}
+	for name, values := range r.headers {
+		req.Header[name] = values
+	}
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("execute mapping request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", fmt.Errorf("read mapping response: %w", err)
+	}
+
+	if resp.StatusCode == http.StatusNotFound {
+		return "", &slackUserNotFoundError{GitHubUser: ghUser}
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("mapping API returned status %d: %s", resp.StatusCode, body)
+	}
+
+	var payload any
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return "", fmt.Errorf("unmarshal mapping response: %w", err)
+	}
+
+	selector := r.selector
+	if selector == nil {
+		selector = jsonSelector{defaultMappingSelector}
+	}
+	id, ok := selector.selectString(payload)
+	if !ok || id == "" {
+		return "", &slackUserNotFoundError{GitHubUser: ghUser}
+	}
+	return id, nil
+}
+
+// jsonSelector is a path into a decoded JSON document: object keys (string)
+// and array indexes (int).
+type jsonSelector []any
+
+// parseJSONSelector parses a JSONPath-like selector such as "user.slack",
+// "$.users[0].id" or "[0].slack_id".
+func parseJSONSelector(s string) (jsonSelector, error) {
+	rest := strings.TrimPrefix(strings.TrimSpace(s), "$")
+	if rest == "" {
+		return nil, errors.New("empty selector")
+	}
+
+	var sel jsonSelector
+	for first := true; rest != ""; first = false {
+		switch {
+		case rest[0] == '[':
+			end := strings.IndexByte(rest, ']')
+			if end < 0 {
+				return nil, fmt.Errorf("unclosed [ in %q", s)
+			}
+			i, err := strconv.Atoi(rest[1:end])
+			if err != nil || i < 0 {
+				return nil, fmt.Errorf("invalid index %q in %q", rest[1:end], s)
+			}
+			sel = append(sel, i)
+			rest = rest[end+1:]
+		case rest[0] == '.' || first:
+			rest = strings.TrimPrefix(rest, ".")
+			end := strings.IndexAny(rest, ".[")
+			if end < 0 {
+				end = len(rest)
+			}
+			if end == 0 {
+				return nil, fmt.Errorf("empty key in %q", s)
+			}
+			sel = append(sel, rest[:end])
+			rest = rest[end:]
+		default:
+			return nil, fmt.Errorf("unexpected %q in %q", rest[0], s)
+		}
+	}
+	return sel, nil
+}
+
+// selectString follows the path through v and returns the string at its end.
+func (sel jsonSelector) selectString(v any) (string, bool) {
+	for _, step := range sel {
+		switch step := step.(type) {
+		case string:
+			obj, ok := v.(map[string]any)
+			if !ok {
+				return "", false
+			}
+			v = obj[step]
+		case int:
+			arr, ok := v.([]any)
+			if !ok || step >= len(arr) {
+				return "", false
+			}
+			v = arr[step]
+		}
+	}
+	s, ok := v.(string)
+	return s, ok
+}
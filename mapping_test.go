@@ -0,0 +1,103 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseJSONSelector(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		in        string
+		expected  jsonSelector
+		expectErr bool
+	}{
+		{in: "slack_user_id", expected: jsonSelector{"slack_user_id"}},
+		{in: "user.slack", expected: jsonSelector{"user", "slack"}},
+		{in: "$.users[1].id", expected: jsonSelector{"users", 1, "id"}},
+		{in: "[0].slack_id", expected: jsonSelector{0, "slack_id"}},
+		{in: "$", expectErr: true},
+		{in: "user..slack", expectErr: true},
+		{in: "users[x]", expectErr: true},
+		{in: "users[0", expectErr: true},
+		{in: "users[0]slack", expectErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			t.Parallel()
+			got, err := parseJSONSelector(tt.in)
+			if tt.expectErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.expected, got)
+		})
+	}
+}
+
+func TestEndpointResolver(t *testing.T) {
+	t.Parallel()
+
+	var gotRequest *http.Request
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotRequest = r
+		switch r.URL.Query().Get("github") {
+		case "octocat":
+			_, _ = w.Write([]byte(`{"user":{"slack":"U42"}}`))
+		case "nobody":
+			_, _ = w.Write([]byte(`{"user":{}}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(srv.Close)
+
+	cfg := config{
+		MappingEndpoint:  srv.URL + "/users?github={login_lower}",
+		MappingHeaders:   []string{"X-Team: platform", "X-Trace:  abc:def "},
+		MappingTokenFile: writeTestFile(t, t.TempDir(), "token", "s3cret\n"),
+		MappingSelector:  "$.user.slack",
+	}
+	r, err := newEndpointResolver(cfg, srv.Client())
+	require.NoError(t, err)
+
+	id, err := r.SlackUserID(context.Background(), "OctoCat")
+	require.NoError(t, err)
+	require.Equal(t, "U42", id)
+	require.Equal(t, "Bearer s3cret", gotRequest.Header.Get("Authorization"))
+	require.Equal(t, "platform", gotRequest.Header.Get("X-Team"))
+	require.Equal(t, "abc:def", gotRequest.Header.Get("X-Trace"))
+
+	var notFound *slackUserNotFoundError
+	_, err = r.SlackUserID(context.Background(), "nobody")
+	require.ErrorAs(t, err, &notFound, "selector matches nothing")
+	_, err = r.SlackUserID(context.Background(), "ghost")
+	require.ErrorAs(t, err, &notFound)
+}
+
+func TestNewEndpointResolverErrors(t *testing.T) {
+	t.Parallel()
+
+	_, err := newEndpointResolver(config{MappingHeaders: []string{"no colon"}}, http.DefaultClient)
+	require.Error(t, err)
+
+	_, err = newEndpointResolver(config{MappingTokenFile: "/does/not/exist"}, http.DefaultClient)
+	require.Error(t, err)
+
+	_, err = newEndpointResolver(config{MappingSelector: "a..b"}, http.DefaultClient)
+	require.Error(t, err)
+}
+
+func TestEndpointResolverURL(t *testing.T) {
+	t.Parallel()
+
+	require.Equal(t, "https://example.com/map/octocat", (&endpointResolver{endpoint: "https://example.com/map/"}).url("octocat"))
+	require.Equal(t, "https://example.com/u/Octo%20Cat?lower=octo%20cat", (&endpointResolver{endpoint: "https://example.com/u/{login}?lower={login_lower}"}).url("Octo Cat"))
+}
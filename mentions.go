@@ -0,0 +1,173 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+const mentionLookupConcurrency = 8
+
+var (
+	// mentionTokenRe splits a message into words as containsGitHubUsername
+	// sees them, each optionally preceded by "@".
+	mentionTokenRe = regexp.MustCompile(`@?[A-Za-z0-9_-]+`)
+
+	// githubLoginRe matches a valid GitHub login: alphanumerics and single
+	// hyphens, at most 39 characters.
+	githubLoginRe = regexp.MustCompile(`^[A-Za-z0-9](?:[A-Za-z0-9]|-[A-Za-z0-9]){0,38}$`)
+
+	// verbatimSpanRe matches the parts of mrkdwn where a login must be left
+	// alone: <...> entities (links, mentions) and code spans and blocks.
+	verbatimSpanRe = regexp.MustCompile("```(?s:.*?)```|`[^`\n]+`|<[^<>\n]*>")
+)
+
+// specialMentions are written @here and friends in Slack-flavored text and
+// are never GitHub logins.
+var specialMentions = map[string]struct{}{"here": {}, "channel": {}, "everyone": {}}
+
+// mentionToken is a word of the message with its position.
+type mentionToken struct {
+	start, end int
+	login      string
+	at         bool
+}
+
+// mentionTokens returns the words of message that could name a GitHub user.
+// "@login" inside an email address is skipped, and so is every word inside a
+// <...> entity, such as a link URL or label, or a code span.
+func mentionTokens(message string) []mentionToken {
+	verbatim := verbatimSpanRe.FindAllStringIndex(message, -1)
+	var tokens []mentionToken
+	for _, loc := range mentionTokenRe.FindAllStringIndex(message, -1) {
+		for len(verbatim) > 0 && verbatim[0][1] <= loc[0] {
+			verbatim = verbatim[1:]
+		}
+		if len(verbatim) > 0 && verbatim[0][0] < loc[1] {
+			continue
+		}
+		tok := mentionToken{start: loc[0], end: loc[1], login: message[loc[0]:loc[1]]}
+		if login, ok := strings.CutPrefix(tok.login, "@"); ok {
+			if loc[0] > 0 && isWordByte(message[loc[0]-1]) {
+				continue
+			}
+			tok.login, tok.at = login, true
+		}
+		if githubLoginRe.MatchString(tok.login) {
+			tokens = append(tokens, tok)
+		}
+	}
+	return tokens
+}
+
+func isWordByte(b byte) bool {
+	return b == '_' || 'a' <= b && b <= 'z' || 'A' <= b && b <= 'Z' || '0' <= b && b <= '9'
+}
+
+// resolveGitHubUsers looks every login up in the mapping at once and
+// returns the Slack IDs keyed by lowercased login. Logins that can't be
+// resolved are logged and left out.
+func resolveGitHubUsers(ctx context.Context, identities identityResolver, logins []string) map[string]string {
+	var (
+		mu     sync.Mutex
+		wg     sync.WaitGroup
+		sem    = make(chan struct{}, mentionLookupConcurrency)
+		ids    = make(map[string]string, len(logins))
+		failed []string
+	)
+	for _, login := range logins {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			id, err := identities.SlackUserID(ctx, login)
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				var notFoundErr *slackUserNotFoundError
+				if !errors.As(err, &notFoundErr) {
+					slog.Error("Failed to fetch Slack user", "github_user", login, "error", err)
+				}
+				failed = append(failed, login)
+				return
+			}
+			ids[strings.ToLower(login)] = id
+		}()
+	}
+	wg.Wait()
+
+	if len(failed) > 0 {
+		slog.Warn("GitHub users not resolved, leaving them as plain text", "github_users", failed)
+	}
+	return ids
+}
+
+// replaceGitHubMentions swaps every @login with a Slack ID in ids for a
+// <@ID> mention. Logins in bare are also replaced without the "@".
+func replaceGitHubMentions(message string, ids map[string]string, bare map[string]struct{}) string {
+	var b strings.Builder
+	last := 0
+	for _, tok := range mentionTokens(message) {
+		key := strings.ToLower(tok.login)
+		id, ok := ids[key]
+		if !ok {
+			continue
+		}
+		if _, isBare := bare[key]; !tok.at && !isBare {
+			continue
+		}
+		b.WriteString(message[last:tok.start])
+		b.WriteString("<@" + id + ">")
+		last = tok.end
+	}
+	b.WriteString(message[last:])
+	return b.String()
+}
+
+// mentionGitHubUsers replaces the GH_USERS logins, and with SLACK_MENTION_SCAN
+// every @login, in the message with inline Slack mentions.
+func mentionGitHubUsers(ctx context.Context, cfg config, identities identityResolver) string {
+	message := cfg.Message
+	if len(cfg.GitHubUsers) == 0 && !cfg.MentionScan {
+		return message
+	}
+	if identities == nil || !cfg.EnableMentions {
+		slog.Info("Slack mentions disabled or no GitHub-to-Slack mapping configured, skipping inline mentions")
+		return message
+	}
+
+	bare := make(map[string]struct{}, len(cfg.GitHubUsers))
+	for _, login := range cfg.GitHubUsers {
+		if login = strings.TrimPrefix(strings.TrimSpace(login), "@"); login != "" {
+			bare[strings.ToLower(login)] = struct{}{}
+		}
+	}
+
+	// Only look up the logins the message actually contains.
+	seen := make(map[string]struct{})
+	var logins []string
+	for _, tok := range mentionTokens(message) {
+		key := strings.ToLower(tok.login)
+		_, isBare := bare[key]
+		_, isSpecial := specialMentions[key]
+		if !isBare && (!cfg.MentionScan || !tok.at || isSpecial) {
+			continue
+		}
+		if _, ok := seen[key]; ok {
+			continue
+		}
+		seen[key] = struct{}{}
+		logins = append(logins, tok.login)
+	}
+	if len(logins) == 0 {
+		return message
+	}
+
+	ids := resolveGitHubUsers(ctx, identities, logins)
+	return replaceGitHubMentions(message, ids, bare)
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestReplaceGitHubMentions(t *testing.T) {
+	t.Parallel()
+
+	ids := map[string]string{"octocat": "U1", "hubot": "U2"}
+
+	tests := []struct {
+		name     string
+		message  string
+		bare     []string
+		expected string
+	}{
+		{name: "at mention", message: "deployed by @octocat", expected: "deployed by <@U1>"},
+		{name: "case insensitive", message: "cc @OctoCat, @hubot.", expected: "cc <@U1>, <@U2>."},
+		{name: "repeated", message: "@octocat @octocat", expected: "<@U1> <@U1>"},
+		{name: "unresolved", message: "@ghost and @octocat", expected: "@ghost and <@U1>"},
+		{name: "bare only for listed users", message: "octocat and hubot", bare: []string{"octocat"}, expected: "<@U1> and hubot"},
+		{name: "email", message: "mail dev@octocat", expected: "mail dev@octocat"},
+		{name: "longer login", message: "@octocat-bot", expected: "@octocat-bot"},
+		{name: "existing slack mention", message: "<@octocat>", expected: "<@octocat>"},
+		{
+			name:     "link containing the login",
+			message:  "<https://github.com/octocat/repo/pull/1|#1 by @octocat> by octocat",
+			bare:     []string{"octocat"},
+			expected: "<https://github.com/octocat/repo/pull/1|#1 by @octocat> by <@U1>",
+		},
+		{name: "code", message: "run `gh api users/@octocat`, ```octocat\n@octocat``` ask @octocat", bare: []string{"octocat"}, expected: "run `gh api users/@octocat`, ```octocat\n@octocat``` ask <@U1>"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			bare := make(map[string]struct{})
+			for _, login := range tt.bare {
+				bare[login] = struct{}{}
+			}
+			require.Equal(t, tt.expected, replaceGitHubMentions(tt.message, ids, bare))
+		})
+	}
+}
+
+func TestMentionGitHubUsers(t *testing.T) {
+	t.Parallel()
+
+	known := map[string]string{"octocat": "U1", "hubot": "U2", "monalisa": "U3"}
+	newServer := func(t *testing.T, calls *atomic.Int32) *httptest.Server {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			calls.Add(1)
+			id, ok := known[strings.TrimPrefix(r.URL.Path, "/")]
+			if !ok {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			_, _ = w.Write([]byte(`{"slack_user_id":"` + id + `"}`))
+		}))
+		t.Cleanup(srv.Close)
+		return srv
+	}
+
+	tests := []struct {
+		name      string
+		cfg       config
+		message   string
+		expected  string
+		wantCalls int32
+	}{
+		{
+			name:      "listed users",
+			cfg:       config{GitHubUsers: []string{"octocat", "@hubot", "monalisa"}, EnableMentions: true},
+			message:   "octocat and @hubot shipped it",
+			expected:  "<@U1> and <@U2> shipped it",
+			wantCalls: 2,
+		},
+		{
+			name:      "scan",
+			cfg:       config{MentionScan: true, EnableMentions: true},
+			message:   "@octocat @ghost @here hubot",
+			expected:  "<@U1> @ghost @here hubot",
+			wantCalls: 2,
+		},
+		{
+			name:     "mentions disabled",
+			cfg:      config{GitHubUsers: []string{"octocat"}},
+			message:  "octocat",
+			expected: "octocat",
+		},
+		{
+			name:     "nothing to do",
+			cfg:      config{EnableMentions: true},
+			message:  "@octocat",
+			expected: "@octocat",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			var calls atomic.Int32
+			srv := newServer(t, &calls)
+			cfg := tt.cfg
+			cfg.Message = tt.message
+			identities := &endpointResolver{client: srv.Client(), endpoint: srv.URL + "/"}
+			require.Equal(t, tt.expected, mentionGitHubUsers(context.Background(), cfg, identities))
+			require.Equal(t, tt.wantCalls, calls.Load())
+		})
+	}
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/slack-go/slack"
+)
+
+const notifySnippetLength = 200
+
+// defaultNotifyTemplate is the DM sent in notify mode unless
+// SLACK_MENTION_NOTIFY_TEMPLATE replaces it.
+const defaultNotifyTemplate = `You were mentioned in <#{{ .ChannelID }}>{{ with .Permalink }}: {{ link . "view message" }}{{ end }}
+{{- with .Title }}
+*{{ . }}*{{ end }}
+{{- with .Snippet }}
+{{ . }}{{ end }}`
+
+// mentionNotice is what notify mode tells mentioned users about the message,
+// and the dot value of SLACK_MENTION_NOTIFY_TEMPLATE.
+type mentionNotice struct {
+	ChannelID string
+	Permalink string
+	Title     string
+	// Message is the full message text, Snippet its first characters.
+	Message string
+	Snippet string
+	Color   string
+
+	template *template.Template
+}
+
+// parseNotifyTemplate parses SLACK_MENTION_NOTIFY_TEMPLATE, or the default
+// template when it is empty.
+func parseNotifyTemplate(text string) (*template.Template, error) {
+	tmpl, err := template.New("SLACK_MENTION_NOTIFY_TEMPLATE").
+		Funcs(templateFuncs(time.Now)).
+		Option("missingkey=error").
+		Parse(firstNonEmpty(text, defaultNotifyTemplate))
+	if err != nil {
+		return nil, fmt.Errorf("parse SLACK_MENTION_NOTIFY_TEMPLATE template: %w", err)
+	}
+	return tmpl, nil
+}
+
+// newMentionNotice describes the message sent to channelID for notify mode.
+func newMentionNotice(cfg config, channelID, permalink string) mentionNotice {
+	return mentionNotice{
+		ChannelID: channelID,
+		Permalink: permalink,
+		Title:     cfg.Title,
+		Message:   cfg.Message,
+		Snippet:   truncate(notifySnippetLength, strings.Join(strings.Fields(cfg.Message), " ")),
+		Color:     cfg.Color,
+		template:  cfg.notifyTemplate,
+	}
+}
+
+// options renders the DM: the template's text in an attachment carrying the
+// message's color bar, with a plain fallback for notifications.
+func (n mentionNotice) options() ([]slack.MsgOption, error) {
+	tmpl := n.template
+	if tmpl == nil {
+		var err error
+		if tmpl, err = parseNotifyTemplate(""); err != nil {
+			return nil, err
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, n); err != nil {
+		return nil, fmt.Errorf("render SLACK_MENTION_NOTIFY_TEMPLATE template: %w", err)
+	}
+
+	attachment := slack.Attachment{
+		Fallback: fmt.Sprintf("You were mentioned in <#%s>.", n.ChannelID),
+		Color:    n.Color,
+		Blocks: slack.Blocks{BlockSet: []slack.Block{
+			slack.NewSectionBlock(slack.NewTextBlockObject(slack.MarkdownType, buf.String(), false, false), nil, nil),
+		}},
+	}
+	return []slack.MsgOption{
+		slack.MsgOptionText(attachment.Fallback, false),
+		slack.MsgOptionAttachments(attachment),
+	}, nil
+}
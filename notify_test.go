@@ -0,0 +1,84 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// noticeText sends the notice's options through capturePostMessage and returns
+// the fallback text, the attachment's color and its section text.
+func noticeText(t *testing.T, n mentionNotice) (string, string, string) {
+	t.Helper()
+
+	options, err := n.options()
+	require.NoError(t, err)
+	values := capturePostMessage(t, options...)
+
+	var attachments []struct {
+		Color  string `json:"color"`
+		Blocks []struct {
+			Text struct {
+				Text string `json:"text"`
+			} `json:"text"`
+		} `json:"blocks"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(values.Get("attachments")), &attachments))
+	require.Len(t, attachments, 1)
+	require.Len(t, attachments[0].Blocks, 1)
+	return values.Get("text"), attachments[0].Color, attachments[0].Blocks[0].Text.Text
+}
+
+func TestMentionNotice(t *testing.T) {
+	t.Parallel()
+
+	cfg := config{
+		Title:   "Deploy failed",
+		Message: "<@U1> the deploy of\napi failed: " + strings.Repeat("x", 300),
+		Color:   "#ff0000",
+	}
+
+	t.Run("default template", func(t *testing.T) {
+		t.Parallel()
+		fallback, color, text := noticeText(t, newMentionNotice(cfg, "C123", "https://example.slack.com/archives/C123/p111"))
+		require.Equal(t, "You were mentioned in <#C123>.", fallback)
+		require.Equal(t, "#ff0000", color)
+
+		lines := strings.Split(text, "\n")
+		require.Len(t, lines, 3)
+		require.Equal(t, "You were mentioned in <#C123>: <https://example.slack.com/archives/C123/p111|view message>", lines[0])
+		require.Equal(t, "*Deploy failed*", lines[1])
+		require.True(t, strings.HasPrefix(lines[2], "<@U1> the deploy of api failed: xxx"))
+		require.Len(t, []rune(lines[2]), notifySnippetLength)
+	})
+
+	t.Run("without permalink or title", func(t *testing.T) {
+		t.Parallel()
+		_, _, text := noticeText(t, newMentionNotice(config{Message: "hi <@U1>"}, "C123", ""))
+		require.Equal(t, "You were mentioned in <#C123>\nhi <@U1>", text)
+	})
+
+	t.Run("custom template", func(t *testing.T) {
+		t.Parallel()
+		tmpl, err := parseNotifyTemplate(`{{ .Title | slackEscape }} needs you: {{ .Permalink }}`)
+		require.NoError(t, err)
+		cfg := cfg
+		cfg.Title = "A & B"
+		cfg.notifyTemplate = tmpl
+		_, _, text := noticeText(t, newMentionNotice(cfg, "C123", "https://example.slack.com/p1"))
+		require.Equal(t, "A &amp; B needs you: https://example.slack.com/p1", text)
+	})
+
+	t.Run("invalid template", func(t *testing.T) {
+		t.Parallel()
+		_, err := parseNotifyTemplate(`{{ .Title `)
+		require.Error(t, err)
+
+		tmpl, err := parseNotifyTemplate(`{{ .Nope }}`)
+		require.NoError(t, err)
+		_, err = mentionNotice{template: tmpl}.options()
+		require.Error(t, err)
+	})
+}
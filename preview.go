@@ -0,0 +1,467 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"html"
+	"html/template"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/kelseyhightower/envconfig"
+	"github.com/slack-go/slack"
+)
+
+// runPreview renders the message the configuration describes to a standalone
+// HTML file instead of sending it, so changes to a notification can be reviewed
+// without a Slack workspace. Nothing is sent and no API is called: mentions are
+// shown as they appear in the message, GitHub users aren't resolved.
+func runPreview(args []string) error {
+	if len(args) > 1 {
+		return configError(errors.New("usage: preview [output file]"))
+	}
+
+	if os.Getenv("GITHUB_ACTIONS") == "true" {
+		if err := applyInputAliases(); err != nil {
+			return configError(err)
+		}
+	}
+
+	var cfg config
+	if err := envconfig.Process("", &cfg); err != nil {
+		return configError(err)
+	}
+	if err := loadBlocks(&cfg); err != nil {
+		return configError(err)
+	}
+	if err := renderTemplates(&cfg, time.Now); err != nil {
+		return configError(err)
+	}
+	if err := applyCIContext(&cfg, os.Getenv); err != nil {
+		return configError(err)
+	}
+	if err := applyStatus(&cfg); err != nil {
+		return configError(err)
+	}
+
+	page, err := renderPreview(cfg)
+	if err != nil {
+		return err
+	}
+
+	path := "preview.html"
+	switch {
+	case len(args) == 1:
+		path = args[0]
+	case cfg.OutputDir != "":
+		path = filepath.Join(cfg.OutputDir, "preview.html")
+	}
+	if err := os.WriteFile(path, page, 0644); err != nil {
+		return outputError(fmt.Errorf("write preview: %w", err))
+	}
+	slog.Info("Preview written", "path", path)
+	return nil
+}
+
+var previewPage = template.Must(template.New("preview").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>Slack message preview</title>
+<style>
+body { margin: 0; padding: 24px; background: #f8f8f8; color: #1d1c1d; font: 15px/1.47 -apple-system, BlinkMacSystemFont, "Segoe UI", Roboto, Helvetica, Arial, sans-serif; }
+.window { max-width: 720px; margin: 0 auto; background: #fff; border: 1px solid #ddd; border-radius: 8px; }
+.channel { padding: 12px 20px; border-bottom: 1px solid #ddd; font-weight: 900; }
+.notification { padding: 8px 20px; border-bottom: 1px solid #ddd; color: #616061; font-size: 13px; }
+.message { display: flex; padding: 12px 20px; }
+.avatar { flex: none; width: 36px; height: 36px; margin-right: 8px; border-radius: 4px; background: #4a154b; }
+.body { flex: 1; min-width: 0; }
+.sender { font-weight: 900; }
+.sender .app { margin-left: 4px; padding: 0 3px; border-radius: 2px; background: #ddd; color: #616061; font-size: 10px; font-weight: 700; vertical-align: 1px; }
+.attachment { margin-top: 4px; padding-left: 12px; border-left: 4px solid; }
+.block { margin: 4px 0; }
+.fields { display: grid; grid-template-columns: 1fr 1fr; gap: 8px 16px; margin-top: 8px; }
+.header { font-size: 18px; font-weight: 900; }
+.context { color: #616061; font-size: 12px; }
+.context img { width: 16px; height: 16px; margin-right: 4px; vertical-align: middle; border-radius: 2px; }
+.image img { max-width: 360px; border-radius: 4px; }
+.button { display: inline-block; margin-right: 8px; padding: 3px 12px; border: 1px solid #ccc; border-radius: 4px; font-size: 13px; font-weight: 700; }
+.button.primary { border-color: #007a5a; background: #007a5a; color: #fff; }
+.button.danger { border-color: #e01e5a; background: #e01e5a; color: #fff; }
+.unsupported { color: #616061; font-style: italic; }
+hr { border: 0; border-top: 1px solid #ddd; }
+a { color: #1264a3; text-decoration: none; }
+code { padding: 2px 3px; border: 1px solid #ddd; border-radius: 3px; background: #f6f6f6; color: #e01e5a; font: 12px Monaco, Menlo, Consolas, monospace; }
+pre { margin: 4px 0; padding: 8px; border: 1px solid #ddd; border-radius: 4px; background: #f8f8f8; font: 12px Monaco, Menlo, Consolas, monospace; white-space: pre-wrap; }
+blockquote { margin: 4px 0; padding-left: 12px; border-left: 4px solid #ddd; }
+.mention { padding: 0 2px; border-radius: 3px; background: #e8f5fa; color: #1264a3; }
+</style>
+</head>
+<body>
+<div class="window">
+{{- if .Channel}}
+<div class="channel">{{.Channel}}</div>
+{{- end}}
+<div class="notification">Notification text: {{.Fallback}}</div>
+<div class="message">
+<div class="avatar"></div>
+<div class="body">
+<div class="sender">Slack message <span class="app">APP</span></div>
+{{- if .Attachment}}
+<div class="attachment" style="border-color: {{.Color}}">
+{{.Blocks}}
+</div>
+{{- else}}
+{{.Blocks}}
+{{- end}}
+</div>
+</div>
+</div>
+</body>
+</html>
+`))
+
+// renderPreview renders what content(cfg) would send as an HTML page
+// approximating Slack's look.
+func renderPreview(cfg config) ([]byte, error) {
+	body := newMessageBody(cfg)
+	blocks := body.blocks
+	if body.attachment != nil {
+		blocks = body.attachment.Blocks.BlockSet
+	}
+
+	var rendered strings.Builder
+	for _, b := range blocks {
+		rendered.WriteString(renderBlock(b))
+	}
+
+	var buf bytes.Buffer
+	err := previewPage.Execute(&buf, struct {
+		Channel    string
+		Fallback   template.HTML
+		Attachment bool
+		Color      template.CSS
+		Blocks     template.HTML
+	}{
+		Channel:    cfg.Channel,
+		Fallback:   template.HTML(renderMrkdwn(body.fallback)),
+		Attachment: body.attachment != nil,
+		Color:      template.CSS(previewColor(cfg.Color)),
+		Blocks:     template.HTML(rendered.String()),
+	})
+	if err != nil {
+		return nil, outputError(fmt.Errorf("render preview: %w", err))
+	}
+	return buf.Bytes(), nil
+}
+
+var hexColorRe = regexp.MustCompile(`^#?([0-9a-fA-F]{3}|[0-9a-fA-F]{6})$`)
+
+// previewColor returns the CSS color of the attachment bar. Slack also accepts
+// the good, warning and danger keywords.
+func previewColor(color string) string {
+	switch color {
+	case "good":
+		return "#2eb886"
+	case "warning":
+		return "#daa038"
+	case "danger":
+		return "#a30200"
+	}
+	if !hexColorRe.MatchString(color) {
+		return "#ddd"
+	}
+	return "#" + strings.TrimPrefix(color, "#")
+}
+
+// renderBlock renders one Block Kit block. Block types the preview doesn't know
+// are shown as a placeholder so they're not silently missing.
+func renderBlock(b slack.Block) string {
+	var sb strings.Builder
+	switch b := b.(type) {
+	case *slack.SectionBlock:
+		sb.WriteString(`<div class="block section">`)
+		if b.Text != nil {
+			sb.WriteString(renderTextObject(b.Text))
+		}
+		if len(b.Fields) > 0 {
+			sb.WriteString(`<div class="fields">`)
+			for _, f := range b.Fields {
+				sb.WriteString("<div>" + renderTextObject(f) + "</div>")
+			}
+			sb.WriteString("</div>")
+		}
+		sb.WriteString("</div>")
+	case *slack.HeaderBlock:
+		if b.Text != nil {
+			sb.WriteString(`<div class="block header">` + renderTextObject(b.Text) + "</div>")
+		}
+	case *slack.ContextBlock:
+		sb.WriteString(`<div class="block context">`)
+		for i, e := range b.ContextElements.Elements {
+			if i > 0 {
+				sb.WriteString(" ")
+			}
+			switch e := e.(type) {
+			case *slack.TextBlockObject:
+				sb.WriteString(renderTextObject(e))
+			case *slack.ImageBlockElement:
+				if e.ImageURL != nil {
+					sb.WriteString(`<img src="` + html.EscapeString(*e.ImageURL) + `" alt="` + html.EscapeString(e.AltText) + `">`)
+				}
+			}
+		}
+		sb.WriteString("</div>")
+	case *slack.DividerBlock:
+		sb.WriteString("<hr>")
+	case *slack.ImageBlock:
+		sb.WriteString(`<div class="block image">`)
+		if b.Title != nil {
+			sb.WriteString("<div>" + renderTextObject(b.Title) + "</div>")
+		}
+		sb.WriteString(`<img src="` + html.EscapeString(b.ImageURL) + `" alt="` + html.EscapeString(b.AltText) + `">`)
+		sb.WriteString("</div>")
+	case *slack.ActionBlock:
+		sb.WriteString(`<div class="block actions">`)
+		if b.Elements != nil {
+			for _, e := range b.Elements.ElementSet {
+				button, ok := e.(*slack.ButtonBlockElement)
+				if !ok {
+					sb.WriteString(`<span class="unsupported">[` + html.EscapeString(string(e.ElementType())) + `]</span>`)
+					continue
+				}
+				class := "button"
+				if button.Style != "" {
+					class += " " + string(button.Style)
+				}
+				sb.WriteString(`<span class="` + html.EscapeString(class) + `">` + renderTextObject(button.Text) + "</span>")
+			}
+		}
+		sb.WriteString("</div>")
+	default:
+		sb.WriteString(`<div class="block unsupported">[` + html.EscapeString(string(b.BlockType())) + ` block]</div>`)
+	}
+	return sb.String()
+}
+
+func renderTextObject(t *slack.TextBlockObject) string {
+	if t == nil {
+		return ""
+	}
+	if t.Type == slack.PlainTextType {
+		return strings.ReplaceAll(renderEmoji(html.EscapeString(t.Text)), "\n", "<br>")
+	}
+	return renderMrkdwn(t.Text)
+}
+
+// previewEmoji covers the emoji the status presets use; other shortcodes are
+// left as typed.
+var previewEmoji = map[string]string{
+	"white_check_mark":       "✅",
+	"x":                      "❌",
+	"warning":                "⚠️",
+	"no_entry_sign":          "🚫",
+	"hourglass_flowing_sand": "⏳",
+	"fast_forward":           "⏩",
+	"rocket":                 "🚀",
+	"tada":                   "🎉",
+	"rotating_light":         "🚨",
+	"heavy_check_mark":       "✔️",
+	"information_source":     "ℹ️",
+	"large_green_circle":     "🟢",
+	"red_circle":             "🔴",
+	"large_yellow_circle":    "🟡",
+	"eyes":                   "👀",
+	"fire":                   "🔥",
+	"construction":           "🚧",
+	"package":                "📦",
+	"bell":                   "🔔",
+	"memo":                   "📝",
+}
+
+var emojiRe = regexp.MustCompile(`:([a-z0-9_+-]+):`)
+
+func renderEmoji(s string) string {
+	return emojiRe.ReplaceAllStringFunc(s, func(m string) string {
+		if e, ok := previewEmoji[m[1:len(m)-1]]; ok {
+			return e
+		}
+		return m
+	})
+}
+
+// renderMrkdwn converts Slack mrkdwn to HTML: code blocks, quotes, inline code,
+// bold, italic, strikethrough, links and mentions. Text is escaped; Slack's own
+// &amp;, &lt; and &gt; escapes are shown as the characters they stand for.
+func renderMrkdwn(s string) string {
+	var sb strings.Builder
+	for i, part := range strings.Split(s, "```") {
+		if i%2 == 1 {
+			sb.WriteString("<pre>" + html.EscapeString(slackUnescape(strings.Trim(part, "\n"))) + "</pre>")
+			continue
+		}
+		sb.WriteString(renderMrkdwnLines(part))
+	}
+	return sb.String()
+}
+
+// renderMrkdwnLines renders text outside code blocks line by line, grouping
+// consecutive "> " lines into one quote.
+func renderMrkdwnLines(s string) string {
+	var out []string
+	var quote []string
+	flush := func() {
+		if len(quote) > 0 {
+			out = append(out, "<blockquote>"+strings.Join(quote, "<br>")+"</blockquote>")
+			quote = nil
+		}
+	}
+	for _, line := range strings.Split(s, "\n") {
+		if rest, ok := quotedLine(line); ok {
+			quote = append(quote, renderInline(rest))
+			continue
+		}
+		flush()
+		out = append(out, renderInline(line))
+	}
+	flush()
+
+	var sb strings.Builder
+	for i, line := range out {
+		// Quotes are blocks already; other lines are joined with line breaks.
+		if i > 0 && !strings.HasPrefix(line, "<blockquote>") && !strings.HasPrefix(out[i-1], "<blockquote>") {
+			sb.WriteString("<br>")
+		}
+		sb.WriteString(line)
+	}
+	return sb.String()
+}
+
+func quotedLine(line string) (string, bool) {
+	for _, prefix := range []string{"&gt;", ">"} {
+		if rest, ok := strings.CutPrefix(line, prefix); ok {
+			return strings.TrimPrefix(rest, " "), true
+		}
+	}
+	return "", false
+}
+
+// slackEntityRe matches the <...> entities of mrkdwn: links, user, channel and
+// group mentions, and special mentions.
+var slackEntityRe = regexp.MustCompile("<([^<>\n]+)>|`([^`\n]+)`")
+
+// renderInline renders one line. Entities and inline code are rendered first
+// and stood in for by placeholders, so emphasis can span them but never
+// applies inside them.
+func renderInline(s string) string {
+	var rendered []string
+	placeholder := func(h string) string {
+		rendered = append(rendered, h)
+		return fmt.Sprintf("\x00%d\x00", len(rendered)-1)
+	}
+
+	s = slackEntityRe.ReplaceAllStringFunc(s, func(m string) string {
+		if m[0] == '`' {
+			return placeholder("<code>" + html.EscapeString(slackUnescape(m[1:len(m)-1])) + "</code>")
+		}
+		return placeholder(renderEntity(m[1 : len(m)-1]))
+	})
+
+	s = renderEmoji(html.EscapeString(slackUnescape(s)))
+	s = applyEmphasis(s, '*', "b")
+	s = applyEmphasis(s, '_', "i")
+	s = applyEmphasis(s, '~', "s")
+
+	for i, h := range rendered {
+		s = strings.Replace(s, fmt.Sprintf("\x00%d\x00", i), h, 1)
+	}
+	return s
+}
+
+// renderEntity renders the inside of a <...> entity.
+func renderEntity(e string) string {
+	target, label, hasLabel := strings.Cut(e, "|")
+	mention := func(text string) string {
+		return `<span class="mention">` + html.EscapeString(slackUnescape(text)) + "</span>"
+	}
+
+	switch {
+	case strings.HasPrefix(target, "@"):
+		if hasLabel {
+			return mention("@" + label)
+		}
+		return mention(target)
+	case strings.HasPrefix(target, "#"):
+		if hasLabel {
+			return mention("#" + label)
+		}
+		return mention(target)
+	case strings.HasPrefix(target, "!subteam^"):
+		if hasLabel {
+			return mention(label)
+		}
+		return mention("@" + strings.TrimPrefix(target, "!subteam^"))
+	case strings.HasPrefix(target, "!date^"):
+		return html.EscapeString(slackUnescape(label))
+	case strings.HasPrefix(target, "!"):
+		return mention("@" + strings.TrimPrefix(target, "!"))
+	}
+
+	text := target
+	if hasLabel {
+		text = label
+	}
+	href := slackUnescape(target)
+	if !strings.HasPrefix(href, "http://") && !strings.HasPrefix(href, "https://") && !strings.HasPrefix(href, "mailto:") {
+		return html.EscapeString(slackUnescape(text))
+	}
+	return `<a href="` + html.EscapeString(href) + `">` + html.EscapeString(slackUnescape(text)) + "</a>"
+}
+
+var slackUnescaper = strings.NewReplacer("&amp;", "&", "&lt;", "<", "&gt;", ">")
+
+func slackUnescape(s string) string {
+	return slackUnescaper.Replace(s)
+}
+
+// applyEmphasis wraps marker-delimited spans of the escaped line s in tag. As in
+// Slack, a span opens after a non-word character and before a non-space, and
+// closes after a non-space and before a non-word character.
+func applyEmphasis(s string, marker byte, tag string) string {
+	var sb strings.Builder
+	for {
+		start := -1
+		for i := 0; i < len(s)-1; i++ {
+			if s[i] == marker && (i == 0 || !isWordByte(s[i-1])) && s[i+1] != ' ' && s[i+1] != marker {
+				start = i
+				break
+			}
+		}
+		if start < 0 {
+			break
+		}
+
+		end := -1
+		for j := start + 2; j < len(s); j++ {
+			if s[j] == marker && s[j-1] != ' ' && (j == len(s)-1 || !isWordByte(s[j+1])) {
+				end = j
+				break
+			}
+		}
+		if end < 0 {
+			sb.WriteString(s[:start+1])
+			s = s[start+1:]
+			continue
+		}
+
+		sb.WriteString(s[:start] + "<" + tag + ">" + s[start+1:end] + "</" + tag + ">")
+		s = s[end+1:]
+	}
+	sb.WriteString(s)
+	return sb.String()
+}
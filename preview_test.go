@@ -0,0 +1,125 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRenderMrkdwn(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{"plain", "hello", "hello"},
+		{"escaping", "a < b &amp; c &lt;d&gt; \"x\"", "a &lt; b &amp; c &lt;d&gt; &#34;x&#34;"},
+		{"bold", "*Deploy* failed", "<b>Deploy</b> failed"},
+		{"italic and strike", "_maybe_ ~not~", "<i>maybe</i> <s>not</s>"},
+		{"nested", "*_both_*", "<b><i>both</i></b>"},
+		{"no emphasis inside words", "snake_case_name and 2*3*4", "snake_case_name and 2*3*4"},
+		{"unclosed", "a * b and *c", "a * b and *c"},
+		{"inline code", "run `make *all*` now", "run <code>make *all*</code> now"},
+		{"code block", "before\n```\nx := <1>\n```", "before<br><pre>x := &lt;1&gt;</pre>"},
+		{"quote", "&gt; quoted\n> too\nafter", "<blockquote>quoted<br>too</blockquote>after"},
+		{"newlines", "one\ntwo", "one<br>two"},
+		{"link", "<https://example.com/a?b=1&amp;c=2|the *docs*>", `<a href="https://example.com/a?b=1&amp;c=2">the *docs*</a>`},
+		{"bare link", "<https://example.com>", `<a href="https://example.com">https://example.com</a>`},
+		{"bold link", "*see <https://example.com|here>*", `<b>see <a href="https://example.com">here</a></b>`},
+		{"unsafe link", "<javascript:alert(1)|click>", "click"},
+		{"user mention", "<@U123> and <@U456|alice>", `<span class="mention">@U123</span> and <span class="mention">@alice</span>`},
+		{"channel mention", "<#C123|deploys>", `<span class="mention">#deploys</span>`},
+		{"special mention", "<!here>", `<span class="mention">@here</span>`},
+		{"group mention", "<!subteam^S123|@oncall> <!subteam^S456>", `<span class="mention">@oncall</span> <span class="mention">@S456</span>`},
+		{"date", "<!date^1392734382^{date}|Feb 18, 2014>", "Feb 18, 2014"},
+		{"emoji", ":white_check_mark: done :unknown_one:", "✅ done :unknown_one:"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			require.Equal(t, tt.want, renderMrkdwn(tt.in))
+		})
+	}
+}
+
+func TestPreviewColor(t *testing.T) {
+	t.Parallel()
+
+	require.Equal(t, "#008000", previewColor("#008000"))
+	require.Equal(t, "#abc", previewColor("abc"))
+	require.Equal(t, "#2eb886", previewColor("good"))
+	require.Equal(t, "#ddd", previewColor("red; background: url(x)"))
+}
+
+func TestRenderPreview(t *testing.T) {
+	t.Parallel()
+
+	t.Run("attachment", func(t *testing.T) {
+		t.Parallel()
+		page, err := renderPreview(config{
+			Channel: "#deploys",
+			Color:   "#d00000",
+			Title:   "Deploy 1 < 2",
+			Message: "*api* broke, cc <@U123>",
+			Context: "<https://ci.example.com/1|run 1>",
+		})
+		require.NoError(t, err)
+
+		html := string(page)
+		require.Contains(t, html, `<div class="channel">#deploys</div>`)
+		require.Contains(t, html, `<div class="attachment" style="border-color: #d00000">`)
+		require.Contains(t, html, `<div class="block section"><b>Deploy 1 &lt; 2</b></div>`)
+		require.Contains(t, html, `<div class="block section"><b>api</b> broke, cc <span class="mention">@U123</span></div>`)
+		require.Contains(t, html, `<div class="block context"><a href="https://ci.example.com/1">run 1</a></div>`)
+		require.Contains(t, html, `Notification text: <b>api</b> broke`)
+	})
+
+	t.Run("top-level blocks", func(t *testing.T) {
+		t.Parallel()
+		cfg := config{Title: "fallback"}
+		cfg.blocks, _ = parseBlocks([]byte(`[
+			{"type": "header", "text": {"type": "plain_text", "text": "Release <1.0>"}},
+			{"type": "divider"},
+			{"type": "section", "fields": [{"type": "mrkdwn", "text": "*Env*"}, {"type": "plain_text", "text": "prod"}]},
+			{"type": "actions", "elements": [{"type": "button", "style": "primary", "text": {"type": "plain_text", "text": "Open"}, "url": "https://example.com"}]},
+			{"type": "input", "label": {"type": "plain_text", "text": "x"}, "element": {"type": "plain_text_input"}}
+		]`))
+		require.Len(t, cfg.blocks, 5)
+
+		page, err := renderPreview(cfg)
+		require.NoError(t, err)
+
+		html := string(page)
+		require.NotContains(t, html, `class="attachment"`)
+		require.Contains(t, html, `<div class="block header">Release &lt;1.0&gt;</div><hr>`)
+		require.Contains(t, html, `<div class="fields"><div><b>Env</b></div><div>prod</div></div>`)
+		require.Contains(t, html, `<span class="button primary">Open</span>`)
+		require.Contains(t, html, `<div class="block unsupported">[input block]</div>`)
+	})
+}
+
+func TestRunPreview(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("SLACK_TOKEN", "")
+	t.Setenv("SLACK_OUTPUT_DIR", dir)
+	t.Setenv("SLACK_TITLE", "Hello")
+	t.Setenv("SLACK_STATUS", "failure")
+
+	require.NoError(t, runPreview(nil))
+	page, err := os.ReadFile(filepath.Join(dir, "preview.html"))
+	require.NoError(t, err)
+	require.True(t, strings.HasPrefix(string(page), "<!DOCTYPE html>"))
+	require.Contains(t, string(page), "<b>❌ Hello</b>")
+	require.Contains(t, string(page), "border-color: #d00000")
+
+	path := filepath.Join(dir, "other.html")
+	require.NoError(t, runPreview([]string{path}))
+	require.FileExists(t, path)
+
+	require.Equal(t, exitConfig, exitCodeFor(runPreview([]string{"a", "b"})))
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/slack-go/slack"
+)
+
+// slackReactionClient is the subset of *slack.Client used to react to a
+// message.
+type slackReactionClient interface {
+	AddReactionContext(ctx context.Context, name string, item slack.ItemRef) error
+}
+
+// validateReactions checks SLACK_REACTIONS and SLACK_REACTION_TS, which only
+// work together and react to an existing message instead of sending one.
+func validateReactions(cfg config) error {
+	if cfg.ReactionTs == "" {
+		if len(cfg.Reactions) > 0 {
+			return errors.New("SLACK_REACTIONS requires SLACK_REACTION_TS")
+		}
+		return nil
+	}
+	if len(reactionNames(cfg.Reactions)) == 0 {
+		return errors.New("SLACK_REACTION_TS requires SLACK_REACTIONS")
+	}
+	if cfg.ThreadTs != "" || cfg.UpdateTs != "" || cfg.DeleteTs != "" || cfg.ManifestFile != "" ||
+		cfg.PostAt != "" || cfg.DeleteScheduledID != "" || cfg.EphemeralUser != "" || len(cfg.Files) > 0 {
+		return errors.New("SLACK_REACTION_TS can't be combined with replies, updates, deletes, manifests, scheduled or ephemeral messages or file uploads")
+	}
+	return nil
+}
+
+// reactionNames returns the emoji names with any surrounding colons, so both
+// "eyes" and ":eyes:" work.
+func reactionNames(reactions []string) []string {
+	var names []string
+	for _, r := range reactions {
+		if name := strings.Trim(strings.TrimSpace(r), ":"); name != "" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// addReactions adds every reaction to the message at SLACK_REACTION_TS. A
+// reaction the token already left is not an error, so reruns are safe.
+func addReactions(ctx context.Context, cfg config, client slackReactionClient, res *result) error {
+	ref := slack.NewRefToMessage(cfg.Channel, cfg.ReactionTs)
+	for _, name := range reactionNames(cfg.Reactions) {
+		err := client.AddReactionContext(ctx, name, ref)
+		if err != nil && err.Error() != "already_reacted" {
+			return fmt.Errorf("add reaction %s: %w", name, err)
+		}
+		slog.Info("Reaction added", "channel_id", cfg.Channel, "ts", cfg.ReactionTs, "reaction", name)
+	}
+	res.ChannelID = cfg.Channel
+	res.MessageTs = cfg.ReactionTs
+	return nil
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/slack-go/slack"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeReactionClient implements slackReactionClient, recording the reactions
+// and failing with errs by name.
+type fakeReactionClient struct {
+	errs  map[string]error
+	added []string
+}
+
+func (f *fakeReactionClient) AddReactionContext(_ context.Context, name string, item slack.ItemRef) error {
+	if err := f.errs[name]; err != nil {
+		return err
+	}
+	f.added = append(f.added, item.Channel+"/"+item.Timestamp+"/"+name)
+	return nil
+}
+
+func TestValidateReactions(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name      string
+		cfg       config
+		expectErr bool
+	}{
+		{name: "unset", cfg: config{ThreadTs: "1.1"}},
+		{name: "reaction", cfg: config{ReactionTs: "1.1", Reactions: []string{"eyes"}}},
+		{name: "reactions without ts", cfg: config{Reactions: []string{"eyes"}}, expectErr: true},
+		{name: "ts without reactions", cfg: config{ReactionTs: "1.1", Reactions: []string{" :: "}}, expectErr: true},
+		{name: "reply", cfg: config{ReactionTs: "1.1", Reactions: []string{"eyes"}, ThreadTs: "1.1"}, expectErr: true},
+		{name: "files", cfg: config{ReactionTs: "1.1", Reactions: []string{"eyes"}, Files: []string{"*.log"}}, expectErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			err := validateReactions(tt.cfg)
+			if tt.expectErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestAddReactions(t *testing.T) {
+	t.Parallel()
+
+	cfg := config{Channel: "C123", ReactionTs: "111.222", Reactions: []string{":white_check_mark:", " eyes", "tada"}}
+
+	client := &fakeReactionClient{errs: map[string]error{"eyes": errors.New("already_reacted")}}
+	res := &result{}
+	require.NoError(t, addReactions(context.Background(), cfg, client, res))
+	require.Equal(t, []string{"C123/111.222/white_check_mark", "C123/111.222/tada"}, client.added)
+	require.Equal(t, "C123", res.ChannelID)
+	require.Equal(t, "111.222", res.MessageTs)
+
+	client = &fakeReactionClient{errs: map[string]error{"eyes": errors.New("invalid_name")}}
+	err := addReactions(context.Background(), cfg, client, &result{})
+	require.ErrorContains(t, err, "add reaction eyes: invalid_name")
+	require.Equal(t, []string{"C123/111.222/white_check_mark"}, client.added)
+}
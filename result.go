@@ -0,0 +1,125 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/slack-go/slack"
+)
+
+// Exit codes, so workflow steps can branch on why a run failed. 2 is left to
+// the Go runtime, which exits with it on a panic.
+const (
+	exitOK              = 0
+	exitUnknown         = 1
+	exitAuth            = 3
+	exitChannelNotFound = 4
+	exitRateLimited     = 5
+	exitNetwork         = 6
+	exitOutputWrite     = 7
+	exitConfig          = 8
+)
+
+// exitError attaches an exit code to an error whose cause can't be inferred
+// from the error value alone (bad configuration, failed output writes).
+type exitError struct {
+	code int
+	err  error
+}
+
+func (e *exitError) Error() string { return e.err.Error() }
+func (e *exitError) Unwrap() error { return e.err }
+
+func configError(err error) error {
+	return &exitError{code: exitConfig, err: err}
+}
+
+func outputError(err error) error {
+	return &exitError{code: exitOutputWrite, err: err}
+}
+
+// exitCodeFor maps an error returned by run to the process exit code.
+func exitCodeFor(err error) int {
+	if err == nil {
+		return exitOK
+	}
+
+	var exitErr *exitError
+	if errors.As(err, &exitErr) {
+		return exitErr.code
+	}
+
+	var rateLimited *slack.RateLimitedError
+	if errors.As(err, &rateLimited) {
+		return exitRateLimited
+	}
+
+	var slackErr slack.SlackErrorResponse
+	if errors.As(err, &slackErr) {
+		switch slackErr.Err {
+		case "not_authed", "invalid_auth", "account_inactive", "token_revoked", "token_expired", "missing_scope":
+			return exitAuth
+		case "channel_not_found", "not_in_channel", "is_archived":
+			return exitChannelNotFound
+		}
+	}
+
+	if isTransient(err) {
+		return exitNetwork
+	}
+	return exitUnknown
+}
+
+// result is written to result.json in the output directory after every run,
+// successful or not.
+type result struct {
+	// Operation is what the run did, see operation.
+	Operation       string            `json:"operation,omitempty"`
+	ChannelID       string            `json:"channel_id,omitempty"`
+	MessageTs       string            `json:"message_ts,omitempty"`
+	ThreadTs        string            `json:"thread_ts,omitempty"`
+	Permalink       string            `json:"permalink,omitempty"`
+	ThreadPermalink string            `json:"thread_permalink,omitempty"`
+	FileIDs         []string          `json:"file_ids,omitempty"`
+	RootUpdate      *rootUpdateResult `json:"root_update,omitempty"`
+	Error           *resultError      `json:"error,omitempty"`
+	DryRun          bool              `json:"dry_run,omitempty"`
+
+	// ScheduledMessageID and PostAt are set for scheduled messages, which have
+	// no timestamp or permalink until Slack posts them.
+	ScheduledMessageID string `json:"scheduled_message_id,omitempty"`
+	PostAt             string `json:"post_at,omitempty"`
+
+	// Channels holds the per-channel results, keyed by the channel as given in
+	// SLACK_CHANNEL, when posting to several channels.
+	Channels map[string]*result `json:"channels,omitempty"`
+}
+
+// rootUpdateResult describes the thread root restyled after a reply.
+type rootUpdateResult struct {
+	MessageTs string `json:"message_ts"`
+	Permalink string `json:"permalink,omitempty"`
+}
+
+type resultError struct {
+	ExitCode int    `json:"exit_code"`
+	Message  string `json:"message"`
+}
+
+func (r *result) setError(err error) {
+	r.Error = &resultError{ExitCode: exitCodeFor(err), Message: err.Error()}
+}
+
+func writeResult(dir string, res *result) error {
+	data, err := json.MarshalIndent(res, "", "  ")
+	if err != nil {
+		return outputError(fmt.Errorf("marshal result: %w", err))
+	}
+	if err := os.WriteFile(filepath.Join(dir, "result.json"), data, 0644); err != nil {
+		return outputError(fmt.Errorf("write result.json: %w", err))
+	}
+	return nil
+}
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/slack-go/slack"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExitCodeFor(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		err      error
+		expected int
+	}{
+		{name: "nil", err: nil, expected: exitOK},
+		{name: "config", err: configError(errors.New("bad")), expected: exitConfig},
+		{name: "output", err: outputError(errors.New("disk full")), expected: exitOutputWrite},
+		{name: "auth", err: fmt.Errorf("post message: %w", slack.SlackErrorResponse{Err: "invalid_auth"}), expected: exitAuth},
+		{name: "channel not found", err: slack.SlackErrorResponse{Err: "channel_not_found"}, expected: exitChannelNotFound},
+		{name: "rate limited", err: &slack.RateLimitedError{RetryAfter: time.Minute}, expected: exitRateLimited},
+		{name: "network", err: &net.OpError{Op: "dial", Err: errors.New("refused")}, expected: exitNetwork},
+		{name: "server error", err: slack.StatusCodeError{Code: 502, Status: "502 Bad Gateway"}, expected: exitNetwork},
+		{name: "unknown", err: errors.New("boom"), expected: exitUnknown},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			require.Equal(t, tt.expected, exitCodeFor(tt.err))
+		})
+	}
+}
+
+func readOutput(t *testing.T, dir, name string) string {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join(dir, name))
+	require.NoError(t, err)
+	return string(data)
+}
+
+func TestExecute(t *testing.T) {
+	t.Parallel()
+
+	t.Run("post writes outputs", func(t *testing.T) {
+		t.Parallel()
+		dir := t.TempDir()
+		res := &result{}
+		err := execute(context.Background(), config{Channel: "C123", Message: "hi", OutputDir: dir}, membershipModeNone, &flakySlackAPI{}, res)
+		require.NoError(t, err)
+		require.Equal(t, "post", res.Operation)
+		require.Equal(t, "C123", readOutput(t, dir, "channel-id"))
+		require.Equal(t, "111.222", readOutput(t, dir, "message-ts"))
+		require.Equal(t, "111.222", readOutput(t, dir, "thread-ts"))
+		require.Equal(t, "https://example.slack.com/archives/C123/p111.222", readOutput(t, dir, "permalink"))
+		require.Equal(t, "https://example.slack.com/archives/C123/p111.222", readOutput(t, dir, "thread-permalink"))
+	})
+
+	t.Run("reply keeps thread ts", func(t *testing.T) {
+		t.Parallel()
+		res := &result{}
+		err := execute(context.Background(), config{Channel: "C123", ThreadTs: "100.000"}, membershipModeNone, &flakySlackAPI{}, res)
+		require.NoError(t, err)
+		require.Equal(t, "reply", res.Operation)
+		require.Equal(t, "100.000", res.ThreadTs)
+		require.Equal(t, "111.222", res.MessageTs)
+		require.Equal(t, "https://example.slack.com/archives/C123/p111.222", res.Permalink)
+		require.Equal(t, "https://example.slack.com/archives/C123/p100.000", res.ThreadPermalink)
+	})
+
+	t.Run("react sends nothing", func(t *testing.T) {
+		t.Parallel()
+		dir := t.TempDir()
+		res := &result{}
+		api := &flakySlackAPI{}
+		err := execute(context.Background(), config{Channel: "C123", ReactionTs: "100.000", Reactions: []string{"eyes"}, OutputDir: dir}, membershipModeNone, api, res)
+		require.NoError(t, err)
+		require.Equal(t, "react", res.Operation)
+		require.Zero(t, api.sendCalls)
+		require.Equal(t, []string{"C123/100.000/eyes"}, api.reactions)
+		require.Equal(t, "100.000", readOutput(t, dir, "message-ts"))
+		require.NoFileExists(t, filepath.Join(dir, "manifest.json"))
+	})
+
+	t.Run("upload sends no message", func(t *testing.T) {
+		t.Parallel()
+		dir := t.TempDir()
+		path := writeTestFile(t, dir, "report.xml", "<ok/>")
+		res := &result{}
+		api := &flakySlackAPI{}
+		err := execute(context.Background(), config{command: commandUpload, Channel: "C123", ThreadTs: "100.000", Files: []string{path}, OutputDir: dir}, membershipModeNone, api, res)
+		require.NoError(t, err)
+		require.Equal(t, "upload", res.Operation)
+		require.Zero(t, api.sendCalls)
+		require.Equal(t, []string{"Freport.xml"}, res.FileIDs)
+		require.Equal(t, "100.000", api.uploads[0].ThreadTimestamp)
+
+		err = execute(context.Background(), config{command: commandUpload, Channel: "C123", Files: []string{filepath.Join(dir, "*.log")}}, membershipModeNone, api, &result{})
+		require.EqualError(t, err, "SLACK_FILES matched no files to upload")
+	})
+
+	t.Run("send failure", func(t *testing.T) {
+		t.Parallel()
+		dir := t.TempDir()
+		res := &result{}
+		api := &flakySlackAPI{sendErrs: []error{slack.SlackErrorResponse{Err: "channel_not_found"}}}
+		err := execute(context.Background(), config{Channel: "#nope", OutputDir: dir}, membershipModeNone, api, res)
+		require.Error(t, err)
+		require.Equal(t, exitChannelNotFound, exitCodeFor(err))
+		require.NoFileExists(t, filepath.Join(dir, "channel-id"))
+	})
+
+	t.Run("output write failure", func(t *testing.T) {
+		t.Parallel()
+		res := &result{}
+		dir := filepath.Join(t.TempDir(), "missing")
+		err := execute(context.Background(), config{Channel: "C123", OutputDir: dir}, membershipModeNone, &flakySlackAPI{}, res)
+		require.Equal(t, exitOutputWrite, exitCodeFor(err))
+	})
+}
+
+func TestWriteResult(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	res := &result{Operation: "post", ChannelID: "C123", MessageTs: "111.222", ThreadTs: "111.222"}
+	res.setError(&slack.RateLimitedError{RetryAfter: time.Minute})
+	require.NoError(t, writeResult(dir, res))
+
+	var got map[string]any
+	require.NoError(t, json.Unmarshal([]byte(readOutput(t, dir, "result.json")), &got))
+	require.Equal(t, "post", got["operation"])
+	require.Equal(t, "C123", got["channel_id"])
+	require.Equal(t, map[string]any{
+		"exit_code": float64(exitRateLimited),
+		"message":   "slack rate limit exceeded, retry after 1m0s",
+	}, got["error"])
+}
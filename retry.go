@@ -0,0 +1,263 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math/rand/v2"
+	"net"
+	"time"
+
+	"github.com/slack-go/slack"
+)
+
+const retryBaseDelay = time.Second
+
+// slackAPI is every *slack.Client method the tool calls. retryingClient wraps
+// it so each call gets the same retry policy; *slack.Client satisfies it.
+type slackAPI interface {
+	slackMembershipClient
+	slackFileClient
+	slackChannelLister
+	slackUserLookup
+	slackUserGroupClient
+	slackReactionClient
+	SendMessageContext(ctx context.Context, channelID string, options ...slack.MsgOption) (string, string, string, error)
+	GetPermalinkContext(ctx context.Context, params *slack.PermalinkParameters) (string, error)
+	ScheduleMessageContext(ctx context.Context, channelID, postAt string, options ...slack.MsgOption) (string, string, error)
+	DeleteScheduledMessageContext(ctx context.Context, params *slack.DeleteScheduledMessageParameters) (bool, error)
+}
+
+// retryPolicy retries a call on rate limits, 5xx responses and network errors.
+// sleep and random are injectable so tests can run without waiting.
+type retryPolicy struct {
+	maxAttempts int
+	maxWait     time.Duration
+	baseDelay   time.Duration
+	sleep       func(ctx context.Context, d time.Duration) error
+	random      func() float64
+}
+
+func newRetryPolicy(maxAttempts int, maxWait time.Duration) retryPolicy {
+	return retryPolicy{
+		maxAttempts: maxAttempts,
+		maxWait:     maxWait,
+		baseDelay:   retryBaseDelay,
+		sleep:       sleepContext,
+		random:      rand.Float64,
+	}
+}
+
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// do calls fn until it succeeds, returns a non-retryable error or the attempts
+// are exhausted. The last error is returned unchanged so callers can still
+// inspect it with errors.As.
+func (p retryPolicy) do(ctx context.Context, op string, fn func() error) error {
+	attempts := max(p.maxAttempts, 1)
+	for attempt := 1; ; attempt++ {
+		err := fn()
+		if err == nil {
+			return nil
+		}
+		if attempt >= attempts {
+			return err
+		}
+
+		wait, ok := p.backoff(attempt, err)
+		if !ok {
+			return err
+		}
+
+		slog.Warn("Slack call failed, retrying", "op", op, "attempt", attempt, "wait", wait, "error", err)
+		if err := p.sleep(ctx, wait); err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+	}
+}
+
+// backoff returns how long to wait before the next attempt, or false if err is
+// not worth retrying. Rate limits wait exactly as long as Slack asks, unless
+// that exceeds maxWait; everything else uses exponential backoff with jitter.
+func (p retryPolicy) backoff(attempt int, err error) (time.Duration, bool) {
+	var rateLimited *slack.RateLimitedError
+	if errors.As(err, &rateLimited) {
+		if rateLimited.RetryAfter > p.maxWait {
+			return 0, false
+		}
+		return max(rateLimited.RetryAfter, p.baseDelay), true
+	}
+
+	if !isTransient(err) {
+		return 0, false
+	}
+
+	d := min(p.baseDelay<<(attempt-1), p.maxWait)
+	// Equal jitter: wait at least half of the backoff so retries stay spaced out.
+	return d/2 + time.Duration(p.random()*float64(d/2)), true
+}
+
+// isTransient reports whether err is a server-side or network failure.
+func isTransient(err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+
+	var statusErr slack.StatusCodeError
+	if errors.As(err, &statusErr) {
+		return statusErr.Retryable()
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return true
+	}
+
+	var slackErr slack.SlackErrorResponse
+	if errors.As(err, &slackErr) {
+		switch slackErr.Err {
+		case "internal_error", "fatal_error", "service_unavailable", "request_timeout":
+			return true
+		}
+	}
+	return false
+}
+
+// retryingClient decorates a slackAPI so every call goes through the policy.
+type retryingClient struct {
+	api    slackAPI
+	policy retryPolicy
+}
+
+func (c *retryingClient) SendMessageContext(ctx context.Context, channelID string, options ...slack.MsgOption) (respChannel, respTs, text string, err error) {
+	err = c.policy.do(ctx, "send message", func() error {
+		var err error
+		respChannel, respTs, text, err = c.api.SendMessageContext(ctx, channelID, options...)
+		return err
+	})
+	return respChannel, respTs, text, err
+}
+
+func (c *retryingClient) GetConversationsContext(ctx context.Context, params *slack.GetConversationsParameters) (channels []slack.Channel, nextCursor string, err error) {
+	err = c.policy.do(ctx, "conversations.list", func() error {
+		var err error
+		channels, nextCursor, err = c.api.GetConversationsContext(ctx, params)
+		return err
+	})
+	return channels, nextCursor, err
+}
+
+func (c *retryingClient) GetUserByEmailContext(ctx context.Context, email string) (user *slack.User, err error) {
+	err = c.policy.do(ctx, "users.lookupByEmail", func() error {
+		var err error
+		user, err = c.api.GetUserByEmailContext(ctx, email)
+		return err
+	})
+	return user, err
+}
+
+func (c *retryingClient) GetUserGroupsContext(ctx context.Context, options ...slack.GetUserGroupsOption) (groups []slack.UserGroup, err error) {
+	err = c.policy.do(ctx, "usergroups.list", func() error {
+		var err error
+		groups, err = c.api.GetUserGroupsContext(ctx, options...)
+		return err
+	})
+	return groups, err
+}
+
+func (c *retryingClient) GetUserGroupMembersContext(ctx context.Context, userGroup string, options ...slack.GetUserGroupMembersOption) (members []string, err error) {
+	err = c.policy.do(ctx, "usergroups.users.list", func() error {
+		var err error
+		members, err = c.api.GetUserGroupMembersContext(ctx, userGroup, options...)
+		return err
+	})
+	return members, err
+}
+
+func (c *retryingClient) GetUsersInConversationContext(ctx context.Context, params *slack.GetUsersInConversationParameters) (members []string, nextCursor string, err error) {
+	err = c.policy.do(ctx, "conversations.members", func() error {
+		var err error
+		members, nextCursor, err = c.api.GetUsersInConversationContext(ctx, params)
+		return err
+	})
+	return members, nextCursor, err
+}
+
+func (c *retryingClient) InviteUsersToConversationContext(ctx context.Context, channelID string, users ...string) (ch *slack.Channel, err error) {
+	err = c.policy.do(ctx, "conversations.invite", func() error {
+		var err error
+		ch, err = c.api.InviteUsersToConversationContext(ctx, channelID, users...)
+		return err
+	})
+	return ch, err
+}
+
+func (c *retryingClient) OpenConversationContext(ctx context.Context, params *slack.OpenConversationParameters) (ch *slack.Channel, noOp, alreadyOpen bool, err error) {
+	err = c.policy.do(ctx, "conversations.open", func() error {
+		var err error
+		ch, noOp, alreadyOpen, err = c.api.OpenConversationContext(ctx, params)
+		return err
+	})
+	return ch, noOp, alreadyOpen, err
+}
+
+func (c *retryingClient) PostMessageContext(ctx context.Context, channelID string, options ...slack.MsgOption) (respChannel, respTs string, err error) {
+	err = c.policy.do(ctx, "chat.postMessage", func() error {
+		var err error
+		respChannel, respTs, err = c.api.PostMessageContext(ctx, channelID, options...)
+		return err
+	})
+	return respChannel, respTs, err
+}
+
+func (c *retryingClient) UploadFileContext(ctx context.Context, params slack.UploadFileParameters) (file *slack.FileSummary, err error) {
+	err = c.policy.do(ctx, "files.upload", func() error {
+		var err error
+		file, err = c.api.UploadFileContext(ctx, params)
+		return err
+	})
+	return file, err
+}
+
+func (c *retryingClient) GetPermalinkContext(ctx context.Context, params *slack.PermalinkParameters) (permalink string, err error) {
+	err = c.policy.do(ctx, "chat.getPermalink", func() error {
+		var err error
+		permalink, err = c.api.GetPermalinkContext(ctx, params)
+		return err
+	})
+	return permalink, err
+}
+
+func (c *retryingClient) ScheduleMessageContext(ctx context.Context, channelID, postAt string, options ...slack.MsgOption) (respChannel, scheduledID string, err error) {
+	err = c.policy.do(ctx, "chat.scheduleMessage", func() error {
+		var err error
+		respChannel, scheduledID, err = c.api.ScheduleMessageContext(ctx, channelID, postAt, options...)
+		return err
+	})
+	return respChannel, scheduledID, err
+}
+
+func (c *retryingClient) DeleteScheduledMessageContext(ctx context.Context, params *slack.DeleteScheduledMessageParameters) (ok bool, err error) {
+	err = c.policy.do(ctx, "chat.deleteScheduledMessage", func() error {
+		var err error
+		ok, err = c.api.DeleteScheduledMessageContext(ctx, params)
+		return err
+	})
+	return ok, err
+}
+
+func (c *retryingClient) AddReactionContext(ctx context.Context, name string, item slack.ItemRef) error {
+	return c.policy.do(ctx, "reactions.add", func() error {
+		return c.api.AddReactionContext(ctx, name, item)
+	})
+}
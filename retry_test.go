@@ -0,0 +1,234 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/slack-go/slack"
+	"github.com/stretchr/testify/require"
+)
+
+// testRetryPolicy returns a policy whose sleeps are recorded instead of
+// waited on, with jitter pinned to the maximum.
+func testRetryPolicy(maxAttempts int, maxWait time.Duration) (retryPolicy, *[]time.Duration) {
+	var sleeps []time.Duration
+	p := newRetryPolicy(maxAttempts, maxWait)
+	p.sleep = func(_ context.Context, d time.Duration) error {
+		sleeps = append(sleeps, d)
+		return nil
+	}
+	p.random = func() float64 { return 1 }
+	return p, &sleeps
+}
+
+// flakySlackAPI implements slackAPI on top of fakeSlackClient and
+// fakeFileClient, failing SendMessageContext with sendErrs in order (a nil
+// entry is a successful call) before succeeding.
+type flakySlackAPI struct {
+	fakeSlackClient
+	fakeFileClient
+	sendErrs  []error
+	sendCalls int
+
+	scheduledAt      []string
+	deletedScheduled []string
+	reactions        []string
+}
+
+func (f *flakySlackAPI) SendMessageContext(_ context.Context, channelID string, _ ...slack.MsgOption) (string, string, string, error) {
+	f.sendCalls++
+	if len(f.sendErrs) > 0 {
+		err := f.sendErrs[0]
+		f.sendErrs = f.sendErrs[1:]
+		if err != nil {
+			return "", "", "", err
+		}
+	}
+	return channelID, "111.222", "", nil
+}
+
+func (f *flakySlackAPI) GetPermalinkContext(_ context.Context, params *slack.PermalinkParameters) (string, error) {
+	return "https://example.slack.com/archives/" + params.Channel + "/p" + params.Ts, nil
+}
+
+func (f *flakySlackAPI) GetConversationsContext(_ context.Context, _ *slack.GetConversationsParameters) ([]slack.Channel, string, error) {
+	return nil, "", nil
+}
+
+func (f *flakySlackAPI) GetUserByEmailContext(_ context.Context, _ string) (*slack.User, error) {
+	return nil, errors.New("users_not_found")
+}
+
+func (f *flakySlackAPI) GetUserGroupsContext(_ context.Context, _ ...slack.GetUserGroupsOption) ([]slack.UserGroup, error) {
+	return nil, nil
+}
+
+func (f *flakySlackAPI) GetUserGroupMembersContext(_ context.Context, _ string, _ ...slack.GetUserGroupMembersOption) ([]string, error) {
+	return nil, nil
+}
+
+func (f *flakySlackAPI) ScheduleMessageContext(_ context.Context, channelID, postAt string, _ ...slack.MsgOption) (string, string, error) {
+	f.scheduledAt = append(f.scheduledAt, postAt)
+	return channelID, "Q123", nil
+}
+
+func (f *flakySlackAPI) DeleteScheduledMessageContext(_ context.Context, params *slack.DeleteScheduledMessageParameters) (bool, error) {
+	f.deletedScheduled = append(f.deletedScheduled, params.ScheduledMessageID)
+	return true, nil
+}
+
+func (f *flakySlackAPI) AddReactionContext(_ context.Context, name string, item slack.ItemRef) error {
+	f.reactions = append(f.reactions, item.Channel+"/"+item.Timestamp+"/"+name)
+	return nil
+}
+
+func TestRetryPolicyDo(t *testing.T) {
+	t.Parallel()
+
+	netErr := &net.OpError{Op: "dial", Err: errors.New("connection refused")}
+
+	tests := []struct {
+		name       string
+		errs       []error
+		maxWait    time.Duration
+		wantErr    bool
+		wantCalls  int
+		wantSleeps []time.Duration
+	}{
+		{name: "success first try", wantCalls: 1},
+		{
+			name:       "rate limit honors retry after",
+			errs:       []error{&slack.RateLimitedError{RetryAfter: 7 * time.Second}},
+			maxWait:    time.Minute,
+			wantCalls:  2,
+			wantSleeps: []time.Duration{7 * time.Second},
+		},
+		{
+			name:      "rate limit longer than max wait gives up",
+			errs:      []error{&slack.RateLimitedError{RetryAfter: 2 * time.Minute}},
+			maxWait:   time.Minute,
+			wantErr:   true,
+			wantCalls: 1,
+		},
+		{
+			name:       "network errors back off exponentially",
+			errs:       []error{netErr, netErr, netErr},
+			maxWait:    time.Minute,
+			wantCalls:  4,
+			wantSleeps: []time.Duration{time.Second, 2 * time.Second, 4 * time.Second},
+		},
+		{
+			name:       "backoff capped at max wait",
+			errs:       []error{netErr, netErr, netErr},
+			maxWait:    3 * time.Second,
+			wantCalls:  4,
+			wantSleeps: []time.Duration{time.Second, 2 * time.Second, 3 * time.Second},
+		},
+		{
+			name:       "5xx retried",
+			errs:       []error{slack.StatusCodeError{Code: 503, Status: "503 Service Unavailable"}},
+			maxWait:    time.Minute,
+			wantCalls:  2,
+			wantSleeps: []time.Duration{time.Second},
+		},
+		{
+			name:       "wrapped slack internal_error retried",
+			errs:       []error{fmt.Errorf("CompleteUploadExternal: %w", slack.SlackErrorResponse{Err: "internal_error"})},
+			maxWait:    time.Minute,
+			wantCalls:  2,
+			wantSleeps: []time.Duration{time.Second},
+		},
+		{
+			name:      "api error not retried",
+			errs:      []error{slack.SlackErrorResponse{Err: "channel_not_found"}},
+			maxWait:   time.Minute,
+			wantErr:   true,
+			wantCalls: 1,
+		},
+		{
+			name:       "attempts exhausted",
+			errs:       []error{netErr, netErr, netErr, netErr, netErr, netErr},
+			maxWait:    time.Minute,
+			wantErr:    true,
+			wantCalls:  5,
+			wantSleeps: []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 8 * time.Second},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			policy, sleeps := testRetryPolicy(5, tt.maxWait)
+			errs := tt.errs
+			calls := 0
+			err := policy.do(context.Background(), "test", func() error {
+				calls++
+				if len(errs) == 0 {
+					return nil
+				}
+				err := errs[0]
+				errs = errs[1:]
+				return err
+			})
+			if tt.wantErr {
+				require.Error(t, err)
+			} else {
+				require.NoError(t, err)
+			}
+			require.Equal(t, tt.wantCalls, calls)
+			require.Equal(t, tt.wantSleeps, *sleeps)
+		})
+	}
+}
+
+func TestRetryPolicyJitter(t *testing.T) {
+	t.Parallel()
+
+	policy, sleeps := testRetryPolicy(2, time.Minute)
+	policy.random = func() float64 { return 0 }
+	netErr := &net.OpError{Op: "dial", Err: errors.New("connection refused")}
+	err := policy.do(context.Background(), "test", func() error { return netErr })
+	require.ErrorIs(t, err, netErr)
+	require.Equal(t, []time.Duration{500 * time.Millisecond}, *sleeps)
+}
+
+func TestRetryPolicyContextCanceled(t *testing.T) {
+	t.Parallel()
+
+	policy := newRetryPolicy(5, time.Minute)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	calls := 0
+	err := policy.do(ctx, "test", func() error {
+		calls++
+		return slack.StatusCodeError{Code: 502, Status: "502 Bad Gateway"}
+	})
+	require.ErrorIs(t, err, context.Canceled)
+	require.Equal(t, 1, calls)
+}
+
+func TestRetryingClient(t *testing.T) {
+	t.Parallel()
+
+	api := &flakySlackAPI{sendErrs: []error{&slack.RateLimitedError{RetryAfter: 3 * time.Second}}}
+	policy, sleeps := testRetryPolicy(3, time.Minute)
+	client := &retryingClient{api: api, policy: policy}
+
+	channelID, ts, _, err := client.SendMessageContext(context.Background(), "C123")
+	require.NoError(t, err)
+	require.Equal(t, "C123", channelID)
+	require.Equal(t, "111.222", ts)
+	require.Equal(t, 2, api.sendCalls)
+	require.Equal(t, []time.Duration{3 * time.Second}, *sleeps)
+
+	// Non-transient errors from the membership calls surface unchanged so
+	// inviteMentionedUsers can still match on them.
+	api.inviteErr = errors.New("already_in_channel")
+	_, err = client.InviteUsersToConversationContext(context.Background(), "C123", "U123")
+	require.EqualError(t, err, "already_in_channel")
+	require.Equal(t, []string{"U123"}, api.invited)
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/slack-go/slack"
+)
+
+// prepareRootUpdate builds the content that replaces the thread root after a
+// reply, from the SLACK_ROOT_UPDATE_* settings. The root is re-rendered in
+// full, so a title or message is required; templates and status presets apply
+// just as they do to the reply.
+func prepareRootUpdate(cfg *config, now func() time.Time) error {
+	if cfg.RootUpdateTitle == "" && cfg.RootUpdateMessage == "" && cfg.RootUpdateContext == "" &&
+		cfg.RootUpdateColor == "" && cfg.RootUpdateStatus == "" {
+		return nil
+	}
+
+	if cfg.ThreadTs == "" || cfg.UpdateTs != "" || cfg.DeleteTs != "" {
+		return errors.New("SLACK_ROOT_UPDATE_* settings require SLACK_THREAD_TS and can't be combined with an update or delete")
+	}
+	if cfg.RootUpdateTitle == "" && cfg.RootUpdateMessage == "" {
+		return errors.New("SLACK_ROOT_UPDATE_TITLE or SLACK_ROOT_UPDATE_MESSAGE is required to update the thread root")
+	}
+
+	root := *cfg
+	root.Title = cfg.RootUpdateTitle
+	root.Message = cfg.RootUpdateMessage
+	root.Context = cfg.RootUpdateContext
+	root.Color = cfg.RootUpdateColor
+	root.Status = cfg.RootUpdateStatus
+	root.blocks = nil
+	root.fallback = ""
+
+	if err := renderTemplates(&root, now); err != nil {
+		return err
+	}
+	if err := applyStatus(&root); err != nil {
+		return err
+	}
+	cfg.rootUpdate = &root
+	return nil
+}
+
+// updateThreadRoot replaces the thread root with the prepared root content and
+// records it in res.
+func updateThreadRoot(ctx context.Context, client slackAPI, root config, channelID, threadTs string, res *result) error {
+	_, ts, _, err := client.SendMessageContext(ctx, channelID, content(root), slack.MsgOptionUpdate(threadTs))
+	if err != nil {
+		return fmt.Errorf("update thread root: %w", err)
+	}
+
+	slog.Info("Thread root updated", "channel_id", channelID, "thread_ts", ts)
+	res.RootUpdate = &rootUpdateResult{MessageTs: ts, Permalink: res.ThreadPermalink}
+	return nil
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/slack-go/slack"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPrepareRootUpdate(t *testing.T) {
+	t.Parallel()
+
+	now := func() time.Time { return time.Unix(0, 0) }
+
+	t.Run("nothing set", func(t *testing.T) {
+		t.Parallel()
+		cfg := config{ThreadTs: "100.000"}
+		require.NoError(t, prepareRootUpdate(&cfg, now))
+		require.Nil(t, cfg.rootUpdate)
+	})
+
+	t.Run("builds root content", func(t *testing.T) {
+		t.Parallel()
+		cfg := config{
+			Template:          true,
+			Title:             "reply title",
+			Message:           "deploy finished",
+			ThreadTs:          "100.000",
+			RootUpdateTitle:   "Deploy {{ \"v1\" }}",
+			RootUpdateStatus:  "success",
+			RootUpdateContext: "ctx",
+		}
+		require.NoError(t, prepareRootUpdate(&cfg, now))
+		require.NotNil(t, cfg.rootUpdate)
+		require.Equal(t, ":white_check_mark: Deploy v1", cfg.rootUpdate.Title)
+		require.Empty(t, cfg.rootUpdate.Message)
+		require.Equal(t, "ctx", cfg.rootUpdate.Context)
+		require.Equal(t, defaultColor, cfg.rootUpdate.Color)
+		require.Equal(t, "reply title", cfg.Title, "the reply itself is untouched")
+	})
+
+	t.Run("requires thread", func(t *testing.T) {
+		t.Parallel()
+		cfg := config{RootUpdateTitle: "done"}
+		require.Error(t, prepareRootUpdate(&cfg, now))
+	})
+
+	t.Run("requires title or message", func(t *testing.T) {
+		t.Parallel()
+		cfg := config{ThreadTs: "100.000", RootUpdateColor: "#00ff00"}
+		require.Error(t, prepareRootUpdate(&cfg, now))
+	})
+}
+
+func TestExecuteRootUpdate(t *testing.T) {
+	t.Parallel()
+
+	root := &config{Title: "done", Color: defaultColor}
+
+	t.Run("reply then update root", func(t *testing.T) {
+		t.Parallel()
+		dir := t.TempDir()
+		api := &flakySlackAPI{}
+		res := &result{}
+		cfg := config{Channel: "C123", ThreadTs: "100.000", OutputDir: dir, rootUpdate: root}
+		require.NoError(t, execute(context.Background(), cfg, membershipModeNone, api, res))
+		require.Equal(t, 2, api.sendCalls)
+		require.Equal(t, "111.222", res.MessageTs)
+		require.Equal(t, &rootUpdateResult{
+			MessageTs: "111.222",
+			Permalink: "https://example.slack.com/archives/C123/p100.000",
+		}, res.RootUpdate)
+		require.Equal(t, "111.222", readOutput(t, dir, "root-message-ts"))
+	})
+
+	t.Run("root update failure still writes reply outputs", func(t *testing.T) {
+		t.Parallel()
+		dir := t.TempDir()
+		api := &flakySlackAPI{sendErrs: []error{nil, slack.SlackErrorResponse{Err: "cant_update_message"}}}
+		res := &result{}
+		cfg := config{Channel: "C123", ThreadTs: "100.000", OutputDir: dir, rootUpdate: root}
+		err := execute(context.Background(), cfg, membershipModeNone, api, res)
+		var slackErr slack.SlackErrorResponse
+		require.True(t, errors.As(err, &slackErr))
+		require.Nil(t, res.RootUpdate)
+		require.Equal(t, "111.222", readOutput(t, dir, "message-ts"))
+	})
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"time"
+
+	"github.com/slack-go/slack"
+)
+
+// parsePostAt accepts an RFC 3339 time or a duration relative to now ("1h30m")
+// and returns the time the message should be posted, which must be in the
+// future.
+func parsePostAt(s string, now time.Time) (time.Time, error) {
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		d, derr := time.ParseDuration(s)
+		if derr != nil {
+			return time.Time{}, fmt.Errorf("invalid SLACK_POST_AT %q: want an RFC 3339 time or a duration like 1h30m", s)
+		}
+		t = now.Add(d)
+	}
+	if !t.After(now) {
+		return time.Time{}, fmt.Errorf("SLACK_POST_AT %q is not in the future", s)
+	}
+	return t, nil
+}
+
+// prepareSchedule validates SLACK_POST_AT and SLACK_DELETE_SCHEDULED_ID.
+// Scheduled messages have no timestamp until Slack posts them, so they can't be
+// combined with anything that needs one.
+func prepareSchedule(cfg *config, now time.Time) error {
+	if cfg.PostAt != "" && cfg.DeleteScheduledID != "" {
+		return errors.New("SLACK_POST_AT and SLACK_DELETE_SCHEDULED_ID are mutually exclusive")
+	}
+	if cfg.PostAt == "" && cfg.DeleteScheduledID == "" {
+		return nil
+	}
+	if cfg.UpdateTs != "" || cfg.DeleteTs != "" || cfg.ManifestFile != "" || cfg.rootUpdate != nil || len(cfg.Files) > 0 {
+		return errors.New("scheduled messages can't be combined with updates, deletes, manifests, root updates or file uploads")
+	}
+
+	if cfg.PostAt != "" {
+		postAt, err := parsePostAt(cfg.PostAt, now)
+		if err != nil {
+			return err
+		}
+		cfg.postAt = postAt
+	}
+	return nil
+}
+
+// scheduleMessage schedules the message with chat.scheduleMessage instead of
+// posting it, recording the scheduled message ID in res.
+func scheduleMessage(ctx context.Context, cfg config, client slackAPI, res *result) error {
+	options := []slack.MsgOption{content(cfg)}
+	if cfg.ThreadTs != "" {
+		options = append(options, slack.MsgOptionTS(cfg.ThreadTs))
+		if cfg.AlsoSendToChannel {
+			options = append(options, slack.MsgOptionBroadcast())
+		}
+	}
+
+	postAt := strconv.FormatInt(cfg.postAt.Unix(), 10)
+	channelID, scheduledID, err := client.ScheduleMessageContext(ctx, cfg.Channel, postAt, options...)
+	if err != nil {
+		return fmt.Errorf("schedule message: %w", err)
+	}
+
+	slog.Info("Message scheduled", "channel_id", channelID, "scheduled_message_id", scheduledID, "post_at", cfg.postAt.Format(time.RFC3339))
+	res.ChannelID = channelID
+	res.ThreadTs = cfg.ThreadTs
+	res.ScheduledMessageID = scheduledID
+	res.PostAt = cfg.postAt.UTC().Format(time.RFC3339)
+	return nil
+}
+
+// deleteScheduledMessage cancels a message scheduled by an earlier run. Slack
+// requires the channel ID here, as with updates.
+func deleteScheduledMessage(ctx context.Context, cfg config, client slackAPI, res *result) error {
+	_, err := client.DeleteScheduledMessageContext(ctx, &slack.DeleteScheduledMessageParameters{
+		Channel:            cfg.Channel,
+		ScheduledMessageID: cfg.DeleteScheduledID,
+	})
+	if err != nil {
+		return fmt.Errorf("delete scheduled message: %w", err)
+	}
+
+	slog.Info("Scheduled message deleted", "channel_id", cfg.Channel, "scheduled_message_id", cfg.DeleteScheduledID)
+	res.ChannelID = cfg.Channel
+	res.ScheduledMessageID = cfg.DeleteScheduledID
+	return nil
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParsePostAt(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name      string
+		in        string
+		expected  time.Time
+		expectErr bool
+	}{
+		{name: "rfc3339", in: "2024-01-01T15:00:00+01:00", expected: time.Date(2024, 1, 1, 14, 0, 0, 0, time.UTC)},
+		{name: "relative", in: "1h30m", expected: time.Date(2024, 1, 1, 13, 30, 0, 0, time.UTC)},
+		{name: "past", in: "2023-12-31T00:00:00Z", expectErr: true},
+		{name: "negative duration", in: "-5m", expectErr: true},
+		{name: "garbage", in: "tomorrow", expectErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			got, err := parsePostAt(tt.in, now)
+			if tt.expectErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.True(t, tt.expected.Equal(got), "got %s", got)
+		})
+	}
+}
+
+func TestPrepareSchedule(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	cfg := config{PostAt: "10m"}
+	require.NoError(t, prepareSchedule(&cfg, now))
+	require.Equal(t, now.Add(10*time.Minute), cfg.postAt)
+
+	require.Error(t, prepareSchedule(&config{PostAt: "10m", DeleteScheduledID: "Q1"}, now))
+	require.Error(t, prepareSchedule(&config{PostAt: "10m", UpdateTs: "1.1"}, now))
+	require.Error(t, prepareSchedule(&config{PostAt: "10m", Files: []string{"*.log"}}, now))
+	require.NoError(t, prepareSchedule(&config{DeleteScheduledID: "Q1"}, now))
+}
+
+func TestExecuteSchedule(t *testing.T) {
+	t.Parallel()
+
+	postAt := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	t.Run("schedule", func(t *testing.T) {
+		t.Parallel()
+		dir := t.TempDir()
+		api := &flakySlackAPI{}
+		res := &result{}
+		cfg := config{Channel: "C123", PostAt: "2030-01-01T00:00:00Z", postAt: postAt, OutputDir: dir}
+		// Left over from an earlier post in the same output directory.
+		for _, name := range []string{"message-ts", "permalink", "file-ids"} {
+			require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte("stale"), 0644))
+		}
+		require.NoError(t, execute(context.Background(), cfg, membershipModeNone, api, res))
+		require.Equal(t, 0, api.sendCalls)
+		require.Equal(t, []string{"1893456000"}, api.scheduledAt)
+		require.Equal(t, "schedule", res.Operation)
+		require.Equal(t, "2030-01-01T00:00:00Z", res.PostAt)
+		require.Equal(t, "Q123", readOutput(t, dir, "scheduled-message-id"))
+		for _, name := range []string{"message-ts", "permalink", "file-ids", "root-message-ts"} {
+			require.Empty(t, readOutput(t, dir, name), name)
+		}
+		require.NoFileExists(t, filepath.Join(dir, "manifest.json"))
+	})
+
+	t.Run("delete scheduled", func(t *testing.T) {
+		t.Parallel()
+		api := &flakySlackAPI{}
+		res := &result{}
+		cfg := config{Channel: "C123", DeleteScheduledID: "Q123"}
+		require.NoError(t, execute(context.Background(), cfg, membershipModeNone, api, res))
+		require.Equal(t, []string{"Q123"}, api.deletedScheduled)
+		require.Equal(t, "delete_scheduled", res.Operation)
+	})
+}
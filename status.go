@@ -0,0 +1,93 @@
+package main
+
+import (
+	"fmt"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+const defaultColor = "#008000"
+
+// statusPreset is the look of a message for a given SLACK_STATUS.
+type statusPreset struct {
+	Color string `yaml:"color"`
+	Emoji string `yaml:"emoji"`
+	// Text is the notification fallback when the message has no title or text.
+	Text string `yaml:"text"`
+}
+
+var defaultStatusPresets = map[string]statusPreset{
+	"success":     {Color: defaultColor, Emoji: ":white_check_mark:", Text: "Succeeded"},
+	"failure":     {Color: "#d00000", Emoji: ":x:", Text: "Failed"},
+	"warning":     {Color: "#daa038", Emoji: ":warning:", Text: "Warning"},
+	"cancelled":   {Color: "#808080", Emoji: ":no_entry_sign:", Text: "Cancelled"},
+	"in_progress": {Color: "#439fe0", Emoji: ":hourglass_flowing_sand:", Text: "In progress"},
+	"skipped":     {Color: "#c0c0c0", Emoji: ":fast_forward:", Text: "Skipped"},
+}
+
+// loadStatusPresets returns the built-in presets, overridden by the YAML (or
+// JSON) map in path, if set. Fields left empty in the file keep their built-in
+// value, and new statuses may be added.
+func loadStatusPresets(path string) (map[string]statusPreset, error) {
+	presets := maps.Clone(defaultStatusPresets)
+	if path == "" {
+		return presets, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read SLACK_STATUS_PRESETS_FILE: %w", err)
+	}
+	var overrides map[string]statusPreset
+	if err := yaml.Unmarshal(data, &overrides); err != nil {
+		return nil, fmt.Errorf("decode SLACK_STATUS_PRESETS_FILE: %w", err)
+	}
+
+	for name, o := range overrides {
+		p := presets[name]
+		if o.Color != "" {
+			p.Color = o.Color
+		}
+		if o.Emoji != "" {
+			p.Emoji = o.Emoji
+		}
+		if o.Text != "" {
+			p.Text = o.Text
+		}
+		presets[name] = p
+	}
+	return presets, nil
+}
+
+// applyStatus styles the message according to SLACK_STATUS. An explicit
+// SLACK_COLOR always wins over the preset color.
+func applyStatus(cfg *config) error {
+	if cfg.Status == "" {
+		if cfg.Color == "" {
+			cfg.Color = defaultColor
+		}
+		return nil
+	}
+
+	presets, err := loadStatusPresets(cfg.StatusPresetsFile)
+	if err != nil {
+		return err
+	}
+	preset, ok := presets[cfg.Status]
+	if !ok {
+		return fmt.Errorf("invalid SLACK_STATUS %q (valid: %s)", cfg.Status, strings.Join(slices.Sorted(maps.Keys(presets)), ", "))
+	}
+
+	if cfg.Color == "" {
+		cfg.Color = firstNonEmpty(preset.Color, defaultColor)
+	}
+	if cfg.Title != "" && preset.Emoji != "" {
+		cfg.Title = preset.Emoji + " " + cfg.Title
+	}
+	cfg.fallback = preset.Text
+	return nil
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestApplyStatus(t *testing.T) {
+	t.Parallel()
+
+	presetsFile := filepath.Join(t.TempDir(), "presets.yaml")
+	require.NoError(t, os.WriteFile(presetsFile, []byte(`
+failure:
+  emoji: ":fire:"
+deployed:
+  color: "#123456"
+  emoji: ":rocket:"
+  text: Deployed
+`), 0644))
+
+	tests := []struct {
+		name      string
+		cfg       config
+		expected  config
+		expectErr bool
+	}{
+		{
+			name:     "no status defaults color",
+			cfg:      config{Title: "deploy"},
+			expected: config{Title: "deploy", Color: defaultColor},
+		},
+		{
+			name:     "no status keeps explicit color",
+			cfg:      config{Color: "#ffffff"},
+			expected: config{Color: "#ffffff"},
+		},
+		{
+			name:     "preset",
+			cfg:      config{Status: "failure", Title: "deploy"},
+			expected: config{Status: "failure", Title: ":x: deploy", Color: "#d00000", fallback: "Failed"},
+		},
+		{
+			name:     "explicit color wins",
+			cfg:      config{Status: "in_progress", Color: "#ffffff"},
+			expected: config{Status: "in_progress", Color: "#ffffff", fallback: "In progress"},
+		},
+		{
+			name: "file overrides field",
+			cfg:  config{Status: "failure", Title: "deploy", StatusPresetsFile: presetsFile},
+			expected: config{
+				Status: "failure", Title: ":fire: deploy", Color: "#d00000", fallback: "Failed",
+				StatusPresetsFile: presetsFile,
+			},
+		},
+		{
+			name: "file adds status",
+			cfg:  config{Status: "deployed", Title: "v1", StatusPresetsFile: presetsFile},
+			expected: config{
+				Status: "deployed", Title: ":rocket: v1", Color: "#123456", fallback: "Deployed",
+				StatusPresetsFile: presetsFile,
+			},
+		},
+		{name: "unknown status", cfg: config{Status: "great"}, expectErr: true},
+		{name: "missing file", cfg: config{Status: "success", StatusPresetsFile: filepath.Join(t.TempDir(), "x.yaml")}, expectErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			cfg := tt.cfg
+			err := applyStatus(&cfg)
+			if tt.expectErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.expected, cfg)
+		})
+	}
+}
+
+func TestLoadStatusPresetsJSON(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "presets.json")
+	require.NoError(t, os.WriteFile(path, []byte(`{"success": {"color": "#00ff00"}}`), 0644))
+
+	presets, err := loadStatusPresets(path)
+	require.NoError(t, err)
+	require.Equal(t, statusPreset{Color: "#00ff00", Emoji: ":white_check_mark:", Text: "Succeeded"}, presets["success"])
+	require.Equal(t, defaultStatusPresets["failure"], presets["failure"])
+}
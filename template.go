@@ -0,0 +1,230 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// templateData is the dot value available to SLACK_TITLE, SLACK_MESSAGE and
+// SLACK_CONTEXT templates.
+type templateData struct {
+	// Env holds the process environment without secrets, e.g.
+	// {{ .Env.GITHUB_SHA }}.
+	Env map[string]string
+	// Data holds the decoded contents of SLACK_TEMPLATE_DATA_FILE, if any.
+	Data any
+}
+
+// renderTemplates renders the title, message and context of cfg as Go
+// templates. Any parse or execution error is returned so the run fails before
+// anything is posted.
+func renderTemplates(cfg *config, now func() time.Time) error {
+	if !cfg.Template {
+		return nil
+	}
+
+	data := templateData{Env: environ()}
+	if cfg.TemplateDataFile != "" {
+		raw, err := os.ReadFile(cfg.TemplateDataFile)
+		if err != nil {
+			return fmt.Errorf("read SLACK_TEMPLATE_DATA_FILE: %w", err)
+		}
+		if err := json.Unmarshal(raw, &data.Data); err != nil {
+			return fmt.Errorf("decode SLACK_TEMPLATE_DATA_FILE: %w", err)
+		}
+	}
+
+	funcs := templateFuncs(now)
+	fields := []struct {
+		name  string
+		value *string
+	}{
+		{"SLACK_TITLE", &cfg.Title},
+		{"SLACK_MESSAGE", &cfg.Message},
+		{"SLACK_CONTEXT", &cfg.Context},
+	}
+	for _, f := range fields {
+		out, err := renderTemplate(f.name, *f.value, funcs, data)
+		if err != nil {
+			return err
+		}
+		*f.value = out
+	}
+	return nil
+}
+
+func renderTemplate(name, text string, funcs template.FuncMap, data templateData) (string, error) {
+	if !strings.Contains(text, "{{") {
+		return text, nil
+	}
+
+	tmpl, err := template.New(name).Funcs(funcs).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return "", fmt.Errorf("parse %s template: %w", name, err)
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("render %s template: %w", name, err)
+	}
+	return buf.String(), nil
+}
+
+func environ() map[string]string {
+	env := make(map[string]string)
+	for _, kv := range os.Environ() {
+		k, v, _ := strings.Cut(kv, "=")
+		if !secretEnv(k) {
+			env[k] = v
+		}
+	}
+	return env
+}
+
+// secretEnv reports whether the variable may hold a secret, which templates
+// must not be able to read: the Slack token, the mapping endpoint settings
+// (including their INPUT_ copies on GitHub Actions) and anything named like a
+// token, secret, password or key.
+func secretEnv(name string) bool {
+	name = strings.ToUpper(name)
+	if strings.Contains(name, "GITHUB_SLACK_MAPPING_") {
+		return true
+	}
+	for _, s := range []string{"TOKEN", "SECRET", "PASSWORD", "PASSWD", "CREDENTIAL", "_KEY", "AUTH"} {
+		if strings.Contains(name, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// templateEnv is the env helper: os.Getenv without secrets.
+func templateEnv(name string) string {
+	if secretEnv(name) {
+		return ""
+	}
+	return os.Getenv(name)
+}
+
+// templateFuncs returns the helpers available in templates. now is injected so
+// tests can pin the clock used by since.
+func templateFuncs(now func() time.Time) template.FuncMap {
+	return template.FuncMap{
+		"env":         templateEnv,
+		"slackEscape": slackEscape,
+		"link":        mrkdwnLink,
+		"truncate":    truncate,
+		"duration": func(v any) (string, error) {
+			d, err := toDuration(v)
+			if err != nil {
+				return "", err
+			}
+			return formatDuration(d), nil
+		},
+		"since": func(v any) (string, error) {
+			t, err := toTime(v)
+			if err != nil {
+				return "", err
+			}
+			return formatDuration(now().Sub(t)), nil
+		},
+	}
+}
+
+// slackEscape escapes the three characters Slack treats as control sequences
+// in mrkdwn text.
+func slackEscape(s string) string {
+	return strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;").Replace(s)
+}
+
+// mrkdwnLink renders a Slack link, or just the escaped text if url is empty.
+func mrkdwnLink(url, text string) string {
+	if url == "" {
+		return slackEscape(text)
+	}
+	return fmt.Sprintf("<%s|%s>", url, slackEscape(text))
+}
+
+// truncate shortens s to at most n runes, replacing the tail with an ellipsis.
+func truncate(n int, s string) string {
+	r := []rune(s)
+	if n <= 0 || len(r) <= n {
+		return s
+	}
+	if n == 1 {
+		return "…"
+	}
+	return string(r[:n-1]) + "…"
+}
+
+// toDuration accepts a time.Duration, a Go duration string ("1m30s") or a
+// number of seconds.
+func toDuration(v any) (time.Duration, error) {
+	switch d := v.(type) {
+	case time.Duration:
+		return d, nil
+	case int:
+		return time.Duration(d) * time.Second, nil
+	case int64:
+		return time.Duration(d) * time.Second, nil
+	case float64:
+		return time.Duration(d * float64(time.Second)), nil
+	case string:
+		if secs, err := strconv.ParseFloat(d, 64); err == nil {
+			return time.Duration(secs * float64(time.Second)), nil
+		}
+		parsed, err := time.ParseDuration(d)
+		if err != nil {
+			return 0, fmt.Errorf("duration: %w", err)
+		}
+		return parsed, nil
+	default:
+		return 0, fmt.Errorf("duration: unsupported type %T", v)
+	}
+}
+
+// toTime accepts a time.Time, an RFC 3339 string or a Unix timestamp in
+// seconds.
+func toTime(v any) (time.Time, error) {
+	switch t := v.(type) {
+	case time.Time:
+		return t, nil
+	case int:
+		return time.Unix(int64(t), 0), nil
+	case int64:
+		return time.Unix(t, 0), nil
+	case float64:
+		return time.Unix(int64(t), 0), nil
+	case string:
+		if secs, err := strconv.ParseInt(t, 10, 64); err == nil {
+			return time.Unix(secs, 0), nil
+		}
+		parsed, err := time.Parse(time.RFC3339, t)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("since: %w", err)
+		}
+		return parsed, nil
+	default:
+		return time.Time{}, fmt.Errorf("since: unsupported type %T", v)
+	}
+}
+
+// formatDuration renders d rounded to the second, e.g. "1h2m3s", dropping the
+// zero-valued trailing units Go would otherwise print ("2m0s" -> "2m").
+func formatDuration(d time.Duration) string {
+	d = d.Round(time.Second)
+	s := d.String()
+	if strings.HasSuffix(s, "m0s") {
+		s = strings.TrimSuffix(s, "0s")
+	}
+	if strings.HasSuffix(s, "h0m") {
+		s = strings.TrimSuffix(s, "0m")
+	}
+	return s
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRenderTemplates(t *testing.T) {
+	t.Setenv("TEMPLATE_TEST_REPO", "grafana/docker-slack-message")
+	t.Setenv("SLACK_TOKEN", "xoxb-secret")
+	t.Setenv("GITHUB_SLACK_MAPPING_ENDPOINT", "https://mapping.example.com/")
+	t.Setenv("INPUT_GITHUB_SLACK_MAPPING_HEADERS", "X-Api-Key: supersecret")
+
+	now := func() time.Time { return time.Date(2024, 1, 1, 12, 5, 30, 0, time.UTC) }
+	dataFile := filepath.Join(t.TempDir(), "data.json")
+	require.NoError(t, os.WriteFile(dataFile, []byte(`{"version":"v1.2.3","tests":{"failed":2}}`), 0644))
+
+	tests := []struct {
+		name      string
+		cfg       config
+		expected  config
+		expectErr bool
+	}{
+		{
+			name:     "plain text untouched",
+			cfg:      config{Template: true, Title: "deploy", Message: "a {literal} brace"},
+			expected: config{Template: true, Title: "deploy", Message: "a {literal} brace"},
+		},
+		{
+			name: "env and data",
+			cfg: config{
+				Template:         true,
+				TemplateDataFile: dataFile,
+				Title:            "{{ .Env.TEMPLATE_TEST_REPO }} {{ .Data.version }}",
+				Message:          "{{ .Data.tests.failed }} failed",
+				Context:          `{{ env "TEMPLATE_TEST_REPO" }}`,
+			},
+			expected: config{
+				Template:         true,
+				TemplateDataFile: dataFile,
+				Title:            "grafana/docker-slack-message v1.2.3",
+				Message:          "2 failed",
+				Context:          "grafana/docker-slack-message",
+			},
+		},
+		{
+			name:     "helpers",
+			cfg:      config{Template: true, Message: `{{ link "https://x" "a<b" }} {{ truncate 4 "abcdef" }} {{ duration 90 }} {{ since "2024-01-01T12:00:00Z" }}`},
+			expected: config{Template: true, Message: "<https://x|a&lt;b> abc… 1m30s 5m30s"},
+		},
+		{
+			name:     "disabled",
+			cfg:      config{Message: "{{ .Env.TEMPLATE_TEST_REPO }}"},
+			expected: config{Message: "{{ .Env.TEMPLATE_TEST_REPO }}"},
+		},
+		{
+			name:     "secrets hidden",
+			cfg:      config{Template: true, Message: `[{{ env "SLACK_TOKEN" }}][{{ env "GITHUB_SLACK_MAPPING_ENDPOINT" }}][{{ env "INPUT_GITHUB_SLACK_MAPPING_HEADERS" }}][{{ index .Env "SLACK_TOKEN" }}]`},
+			expected: config{Template: true, Message: "[][][][]"},
+		},
+		{name: "secret key", cfg: config{Template: true, Message: "{{ .Env.SLACK_TOKEN }}"}, expectErr: true},
+		{name: "parse error", cfg: config{Template: true, Title: "{{ .Env"}, expectErr: true},
+		{name: "missing key", cfg: config{Template: true, Message: "{{ .Env.TEMPLATE_TEST_MISSING }}"}, expectErr: true},
+		{name: "missing data file", cfg: config{Template: true, TemplateDataFile: filepath.Join(t.TempDir(), "nope.json")}, expectErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := tt.cfg
+			err := renderTemplates(&cfg, now)
+			if tt.expectErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.expected, cfg)
+		})
+	}
+}
+
+func TestTruncate(t *testing.T) {
+	t.Parallel()
+
+	require.Equal(t, "abc", truncate(5, "abc"))
+	require.Equal(t, "ab…", truncate(3, "abcdef"))
+	require.Equal(t, "…", truncate(1, "abcdef"))
+	require.Equal(t, "héé…", truncate(4, "hééllo"))
+}
+
+func TestFormatDuration(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		in       time.Duration
+		expected string
+	}{
+		{in: 45 * time.Second, expected: "45s"},
+		{in: 2 * time.Minute, expected: "2m"},
+		{in: time.Hour, expected: "1h"},
+		{in: time.Hour + 2*time.Second, expected: "1h0m2s"},
+		{in: 1500 * time.Millisecond, expected: "2s"},
+	}
+
+	for _, tt := range tests {
+		require.Equal(t, tt.expected, formatDuration(tt.in), "formatDuration(%s)", tt.in)
+	}
+}